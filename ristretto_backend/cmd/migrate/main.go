@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/config"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/db"
+)
+
+const usage = `Usage: migrate [flags] <command>
+
+Commands:
+  up       apply all pending migrations
+  down     revert the most recently applied migrations (see -steps)
+  status   list migrations and whether they have been applied
+
+Flags:
+`
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "print the migrations that would run without applying them")
+	steps := flag.Int("steps", 1, "number of migrations to revert with the down command")
+	verbose := flag.Bool("verbose", false, "print migration SQL in dry-run mode")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+
+	cfg := config.Load()
+
+	database, err := db.Connect(cfg.Database.ConnectionString)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	switch flag.Arg(0) {
+	case "up":
+		migrations, err := database.Migrate(*dryRun)
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		report("apply", "Applied", migrations, *dryRun, *verbose, func(m db.Migration) string { return m.Up })
+	case "down":
+		if *steps < 1 {
+			log.Fatalf("-steps must be at least 1")
+		}
+		migrations, err := database.Rollback(*steps, *dryRun)
+		if err != nil {
+			log.Fatalf("Rollback failed: %v", err)
+		}
+		report("revert", "Reverted", migrations, *dryRun, *verbose, func(m db.Migration) string { return m.Down })
+	case "status":
+		statuses, err := database.MigrationStatus()
+		if err != nil {
+			log.Fatalf("Failed to read migration status: %v", err)
+		}
+		applied := 0
+		for _, s := range statuses {
+			if s.Applied {
+				applied++
+			}
+		}
+		if applied == 0 {
+			fmt.Println("No migrations applied")
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt
+			}
+			fmt.Printf("%04d_%-30s %s\n", s.Version, s.Name, state)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// report prints the migrations that were (or, in dry-run mode, would be) applied or reverted
+func report(action, done string, migrations []db.Migration, dryRun, verbose bool, script func(db.Migration) string) {
+	if len(migrations) == 0 {
+		fmt.Printf("Nothing to %s\n", action)
+		return
+	}
+
+	prefix := done
+	if dryRun {
+		prefix = "Would " + action
+	}
+
+	for _, m := range migrations {
+		fmt.Printf("%s %04d_%s\n", prefix, m.Version, m.Name)
+		if dryRun && verbose {
+			fmt.Println(script(m))
+		}
+	}
+}
@@ -1,53 +1,227 @@
 package main
 
 import (
-	"fmt"
-	"log"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+	_ "time/tzdata" // Timezone data for the places budget day in minimal images
 
 	"github.com/joho/godotenv"
 
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/api/handlers"
 	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/api/middleware"
 	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/api/routes"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/auth"
 	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/config"
 	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/db"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/logging"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/metrics"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/services"
 )
 
 func main() {
 	// Load environment variables
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found")
-	}
+	envErr := godotenv.Load()
 
 	// Load application config
 	cfg := config.Load()
 
+	// Create the logger and route the standard library's logs through it
+	logger := logging.New(os.Stdout, cfg.Logging)
+	slog.SetDefault(logger)
+	if envErr != nil {
+		logger.Info("No .env file found")
+	}
+	logger.Info("Starting server", "environment", cfg.Environment, "mock_fallback", cfg.AllowMockFallback())
+
 	// Initialize database
 	database, err := db.Connect(cfg.Database.ConnectionString)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal(logger, "Failed to connect to database", err)
+	}
+
+	// Apply pending schema migrations if enabled
+	if cfg.Database.AutoMigrate {
+		applied, err := database.Migrate(false)
+		if err != nil {
+			fatal(logger, "Failed to migrate database", err)
+		}
+		logger.Info("Applied migrations", "count", len(applied))
+	}
+
+	// Register metrics for HTTP requests, the database pool, Places API calls
+	// and the places cache
+	registry := metrics.NewRegistry()
+	httpMetrics := metrics.NewHTTPMetrics(registry)
+	placesMetrics := metrics.NewPlacesMetrics(registry)
+	placesCacheMetrics := metrics.NewPlacesCacheMetrics(registry)
+	metrics.RegisterDBStats(registry, database.Stats)
+
+	// Photo URLs must be signed with a key every instance shares; without
+	// one, URLs only work on this instance until it restarts
+	if cfg.Photos.SigningKey == "" {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			fatal(logger, "Failed to generate photo signing key", err)
+		}
+		cfg.Photos.SigningKey = hex.EncodeToString(key)
+		logger.Warn("PHOTO_SIGNING_KEY not set, photo URLs will stop working on restart and on other instances")
+	}
+
+	// Create the places provider
+	placesProvider, err := services.NewPlacesProvider(cfg, database, placesMetrics, logger)
+	if err != nil {
+		fatal(logger, "Failed to create places provider", err)
+	}
+
+	// Put the places cache in front of the provider
+	cacheBackend, err := services.NewCacheBackend(cfg, database)
+	if err != nil {
+		fatal(logger, "Failed to create places cache", err)
+	}
+	if cacheBackend != nil {
+		logger.Info("Using places cache", "backend", cfg.Cache.Backend)
+		placesProvider = services.NewCachedPlacesProvider(placesProvider, cacheBackend, services.CacheTTLs{
+			Nearby:  cfg.Cache.NearbyTTL,
+			Details: cfg.Cache.DetailsTTL,
+			Stale:   cfg.Cache.StaleFor,
+		}, placesCacheMetrics, logger)
+	}
+
+	// Cache proxied photos, which are too large for the places cache
+	photoCache, err := services.NewPhotoCacheBackend(cfg, database)
+	if err != nil {
+		fatal(logger, "Failed to create photo cache", err)
+	}
+
+	// Stop background work and drain requests on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var workers sync.WaitGroup
+
+	// Keep the local coffee shop catalog fresh in the background
+	if cfg.Catalog.SyncEnabled {
+		worker := services.NewCatalogSyncWorker(database, placesProvider,
+			cfg.Catalog.SyncInterval, cfg.Catalog.MaxAge, cfg.Catalog.SyncBatchSize, logger)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			worker.Run(ctx)
+		}()
+	}
+
+	// Delete Postgres cache rows once they are too old to be served stale
+	if cfg.Cache.Backend == services.CacheBackendPostgres || cfg.Photos.CacheBackend == services.PhotoCachePostgres {
+		if cfg.Cache.PruneAfter <= cfg.Cache.StaleFor {
+			logger.Warn("PLACES_CACHE_PRUNE_AFTER is not longer than PLACES_CACHE_STALE_FOR; stale entries may be pruned before they can be served")
+		}
+		worker := services.NewCachePruneWorker(database, time.Hour, cfg.Cache.PruneAfter, logger)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			worker.Run(ctx)
+		}()
+	}
+
+	// Create the Clerk token verifier
+	verifier, err := auth.NewVerifierFromConfig(cfg.Auth)
+	if err != nil {
+		fatal(logger, "Failed to configure token verification", err)
+	}
+	if cfg.Auth.Issuer == "" {
+		logger.Warn("CLERK_ISSUER not set, token issuer will not be checked")
+	}
+
+	// Verify Clerk webhooks if a signing secret is configured
+	var webhookVerifier *auth.WebhookVerifier
+	if cfg.Auth.WebhookSecret != "" {
+		webhookVerifier, err = auth.NewWebhookVerifier(cfg.Auth.WebhookSecret)
+		if err != nil {
+			fatal(logger, "Failed to configure Clerk webhooks", err)
+		}
+	} else {
+		logger.Info("CLERK_WEBHOOK_SECRET not set, Clerk webhooks disabled")
+	}
+
+	// Echoing any origin with credentials lets every site act as the user
+	if cfg.CORS.AllowCredentials && slices.Contains(cfg.CORS.AllowedOrigins, "*") {
+		logger.Warn("CORS allows credentials from any origin; set CORS_ALLOWED_ORIGINS to specific origins")
 	}
 
 	// Create router and register routes
 	mux := http.NewServeMux()
 
 	// Register routes
-	routes.Register(mux, database, middleware.Auth)
+	routes.Register(mux, database, cfg, placesProvider, photoCache, middleware.NewAuth(verifier, logger), webhookVerifier, logger)
 
-	// Health check route (no auth required)
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	})
+	// Liveness and readiness probes (no auth required). /health is kept as
+	// an alias of /livez for existing monitors.
+	healthHandler := handlers.NewHealthHandler(database, placesProvider,
+		cfg.Server.ReadinessPlaces, cfg.Server.ReadinessTimeout, logger)
+	mux.HandleFunc("GET /health", middleware.Route("/health", healthHandler.Livez))
+	mux.HandleFunc("GET /livez", middleware.Route("/livez", healthHandler.Livez))
+	mux.HandleFunc("GET /readyz", middleware.Route("/readyz", healthHandler.Readyz))
 
-	// Determine port
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	// Prometheus metrics, optionally protected by a bearer token
+	if cfg.Metrics.Enabled {
+		mux.Handle("GET /metrics", middleware.Route("/metrics", middleware.MetricsToken(cfg.Metrics.Token, registry).ServeHTTP))
 	}
 
 	// Start server
-	fmt.Printf("Server starting on port %s...\n", port)
-	log.Fatal(http.ListenAndServe(":"+port, mux))
+	server := &http.Server{
+		Addr:              ":" + cfg.ServerPort,
+		Handler:           middleware.RequestID(middleware.AccessLog(logger, middleware.Metrics(httpMetrics, middleware.CORS(cfg.CORS, middleware.StripIdentityHeaders(mux))))),
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info("Server starting", "port", cfg.ServerPort)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		fatal(logger, "Server failed", err)
+	case <-ctx.Done():
+	}
+
+	// Fail readiness first so load balancers stop routing here, then let
+	// in-flight requests finish before closing the database pool. Stopping
+	// signal handling lets a second signal kill the process immediately.
+	logger.Info("Shutting down", "delay", cfg.Server.ShutdownDelay, "timeout", cfg.Server.ShutdownTimeout)
+	stop()
+	healthHandler.SetDraining()
+	time.Sleep(cfg.Server.ShutdownDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("Server did not shut down cleanly", "err", err)
+	}
+	workers.Wait()
+
+	if err := database.Close(); err != nil {
+		logger.Error("Failed to close database", "err", err)
+	}
+	logger.Info("Server stopped")
+}
+
+// fatal logs an error and exits
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "err", err)
+	os.Exit(1)
 }
@@ -0,0 +1,215 @@
+// Package apierror defines the JSON error responses returned by the API
+package apierror
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/httpclient"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/requestid"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/services"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/validation"
+)
+
+// Code is a machine-readable error code clients can branch on
+type Code string
+
+// Error codes returned by the API
+const (
+	CodeInvalidRequest      Code = "invalid_request"
+	CodeInvalidCoordinates  Code = "invalid_coordinates"
+	CodeValidationFailed    Code = "validation_failed"
+	CodeUnauthenticated     Code = "unauthenticated"
+	CodeForbidden           Code = "forbidden"
+	CodeNotFound            Code = "not_found"
+	CodePlaceNotFound       Code = "place_not_found"
+	CodeUserNotFound        Code = "user_not_found"
+	CodeReviewNotFound      Code = "review_not_found"
+	CodeFavoriteNotFound    Code = "favorite_not_found"
+	CodeAlreadyReviewed     Code = "already_reviewed"
+	CodeRateLimited         Code = "rate_limited"
+	CodeQuotaExhausted      Code = "quota_exhausted"
+	CodeUpstreamUnavailable Code = "upstream_unavailable"
+	CodeUpstreamTimeout     Code = "upstream_timeout"
+	CodeInternal            Code = "internal_error"
+)
+
+// Error is an error with the HTTP status and code it should be reported as.
+// Message is shown to clients; the wrapped error is only logged.
+type Error struct {
+	Status  int
+	Code    Code
+	Message string
+	Fields  []validation.FieldError // Invalid fields, for validation errors
+	Err     error
+}
+
+// New creates an Error
+func New(status int, code Code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// Wrap creates an Error that keeps err as its cause
+func Wrap(err error, status int, code Code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message, Err: err}
+}
+
+// Error implements error
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return string(e.Code) + ": " + e.Message + ": " + e.Err.Error()
+	}
+	return string(e.Code) + ": " + e.Message
+}
+
+// Unwrap returns the cause of the error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// BadRequest creates a 400 invalid_request error
+func BadRequest(message string) *Error {
+	return New(http.StatusBadRequest, CodeInvalidRequest, message)
+}
+
+// Unauthenticated creates a 401 unauthenticated error
+func Unauthenticated(message string) *Error {
+	return New(http.StatusUnauthorized, CodeUnauthenticated, message)
+}
+
+// Forbidden creates a 403 forbidden error
+func Forbidden(message string) *Error {
+	return New(http.StatusForbidden, CodeForbidden, message)
+}
+
+// TooManyRequests creates a 429 rate_limited error
+func TooManyRequests(message string) *Error {
+	return New(http.StatusTooManyRequests, CodeRateLimited, message)
+}
+
+// NotFound creates a 404 error with a specific code such as place_not_found
+func NotFound(code Code, message string) *Error {
+	return New(http.StatusNotFound, code, message)
+}
+
+// envelope is the JSON body of every error response
+type envelope struct {
+	Error body `json:"error"`
+}
+
+// body describes a single error
+type body struct {
+	Code      Code                    `json:"code"`
+	Message   string                  `json:"message"`
+	Fields    []validation.FieldError `json:"fields,omitempty"`
+	RequestID string                  `json:"requestId,omitempty"`
+}
+
+// From converts any error into an Error:
+//   - an *Error anywhere in the chain is used as is
+//   - a validation error becomes 400 listing every invalid field
+//   - sql.ErrNoRows becomes 404 not_found
+//   - a photo the places provider cannot serve becomes 404 not_found
+//   - an exhausted Places call budget becomes 503 quota_exhausted
+//   - a places provider error is mapped from its upstream status
+//   - an open circuit breaker becomes 503 upstream_unavailable
+//   - timeouts and network failures become upstream errors
+//   - anything else is a 500 whose details are not exposed
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var validationErr *validation.Error
+	if errors.As(err, &validationErr) {
+		return fromValidationError(validationErr)
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return Wrap(err, http.StatusNotFound, CodeNotFound, "Resource not found")
+	}
+
+	if errors.Is(err, services.ErrPhotosUnsupported) {
+		return Wrap(err, http.StatusNotFound, CodeNotFound, "Photo not found")
+	}
+
+	if errors.Is(err, services.ErrPlacesBudgetExhausted) {
+		return Wrap(err, http.StatusServiceUnavailable, CodeQuotaExhausted, "Coffee shop search is unavailable until tomorrow")
+	}
+
+	var placesErr *services.PlacesAPIError
+	if errors.As(err, &placesErr) {
+		return fromPlacesError(placesErr)
+	}
+
+	if errors.Is(err, httpclient.ErrCircuitOpen) {
+		return Wrap(err, http.StatusServiceUnavailable, CodeUpstreamUnavailable, "Places provider unavailable")
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return Wrap(err, http.StatusGatewayTimeout, CodeUpstreamTimeout, "Upstream service timed out")
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return Wrap(err, http.StatusBadGateway, CodeUpstreamUnavailable, "Upstream service unavailable")
+	}
+
+	return Wrap(err, http.StatusInternalServerError, CodeInternal, "Internal server error")
+}
+
+// fromValidationError reports invalid fields as a 400. Errors limited to
+// coordinates keep the more specific invalid_coordinates code.
+func fromValidationError(err *validation.Error) *Error {
+	code := CodeValidationFailed
+	if err.OnlyCoordinates() {
+		code = CodeInvalidCoordinates
+	}
+	apiErr := Wrap(err, http.StatusBadRequest, code, "Request has invalid fields")
+	apiErr.Fields = err.Fields
+	return apiErr
+}
+
+// fromPlacesError maps a places provider status onto the status we return.
+// Failures that are not the client's fault are reported as upstream errors.
+func fromPlacesError(err *services.PlacesAPIError) *Error {
+	switch {
+	case err.NotFound():
+		return Wrap(err, http.StatusNotFound, CodePlaceNotFound, "Coffee shop not found")
+	case err.StatusCode == http.StatusBadRequest:
+		return Wrap(err, http.StatusBadRequest, CodeInvalidRequest, "Invalid request for places provider")
+	case err.StatusCode == http.StatusTooManyRequests:
+		return Wrap(err, http.StatusServiceUnavailable, CodeUpstreamUnavailable, "Places provider is rate limiting requests")
+	default:
+		return Wrap(err, http.StatusBadGateway, CodeUpstreamUnavailable, "Places provider unavailable")
+	}
+}
+
+// Write sends err as a JSON error envelope. Server-side failures are logged
+// with their cause, which is never sent to the client.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := From(err)
+	id := requestid.FromContext(r.Context())
+
+	if apiErr.Status >= http.StatusInternalServerError {
+		slog.ErrorContext(r.Context(), "Request failed", "method", r.Method, "path", r.URL.Path, "status", apiErr.Status, "err", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(apiErr.Status)
+	json.NewEncoder(w).Encode(envelope{
+		Error: body{
+			Code:      apiErr.Code,
+			Message:   apiErr.Message,
+			Fields:    apiErr.Fields,
+			RequestID: id,
+		},
+	})
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/api/apierror"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/auth"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/db"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/services"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/pkg/utils"
+)
+
+// Page size bounds for the admin user list
+const (
+	defaultAdminPageSize = 50
+	maxAdminPageSize     = 200
+)
+
+// AdminHandler handles admin and moderation requests under /admin. Role
+// checks are applied by middleware when the routes are registered.
+type AdminHandler struct {
+	db            *db.DB
+	placesService services.PlacesProvider
+	logger        *slog.Logger
+}
+
+// NewAdminHandler creates a new AdminHandler
+func NewAdminHandler(db *db.DB, placesService services.PlacesProvider, logger *slog.Logger) *AdminHandler {
+	return &AdminHandler{
+		db:            db,
+		placesService: placesService,
+		logger:        logger,
+	}
+}
+
+// ListUsers handles GET /admin/users, listing users a page at a time using
+// limit and offset query params
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	limit := defaultAdminPageSize
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = min(l, maxAdminPageSize)
+	}
+	offset := 0
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o > 0 {
+		offset = o
+	}
+
+	users, err := h.db.ListUsers(limit, offset)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.UsersResponse{
+		Users: users,
+	})
+}
+
+// GetUser handles GET /admin/users/{userId}, returning a user's profile
+func (h *AdminHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := adminUserID(w, r)
+	if !ok {
+		return
+	}
+
+	profile, err := h.db.GetUserProfile(userID)
+	if err == sql.ErrNoRows {
+		apierror.Write(w, r, apierror.NotFound(apierror.CodeUserNotFound, "User not found"))
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profile)
+}
+
+// SetUserRole handles PUT /admin/users/{userId}/role, changing a user's role
+func (h *AdminHandler) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	identity, ok := requireIdentity(w, r)
+	if !ok {
+		return
+	}
+	userID, ok := adminUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.InfoContext(r.Context(), "Invalid request body", "err", err)
+		apierror.Write(w, r, apierror.BadRequest("Invalid request body"))
+		return
+	}
+	if !auth.ValidRole(req.Role) {
+		apierror.Write(w, r, apierror.BadRequest("Role must be one of user, moderator or admin"))
+		return
+	}
+
+	// Keep admins from locking themselves out
+	if userID == identity.UserID && req.Role != auth.RoleAdmin {
+		apierror.Write(w, r, apierror.BadRequest("You cannot remove your own admin role"))
+		return
+	}
+
+	rowsAffected, err := h.db.SetUserRole(userID, req.Role)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	if rowsAffected == 0 {
+		apierror.Write(w, r, apierror.NotFound(apierror.CodeUserNotFound, "User not found"))
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "Set user role", "admin_id", identity.UserID, "user_id", userID, "role", req.Role)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Role updated",
+	})
+}
+
+// GetUserFavorites handles GET /admin/users/{userId}/favorites, returning
+// another user's favorite coffee shops
+func (h *AdminHandler) GetUserFavorites(w http.ResponseWriter, r *http.Request) {
+	userID, ok := adminUserID(w, r)
+	if !ok {
+		return
+	}
+
+	favorites, err := h.db.GetFavorites(userID)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"favorites": favorites,
+	})
+}
+
+// GetUserVisits handles GET /admin/users/{userId}/visits, returning another
+// user's recent visits
+func (h *AdminHandler) GetUserVisits(w http.ResponseWriter, r *http.Request) {
+	userID, ok := adminUserID(w, r)
+	if !ok {
+		return
+	}
+
+	visits, err := h.db.GetVisits(userID)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"visits": visits,
+	})
+}
+
+// RefreshCoffeeShop handles POST /admin/coffee_shops/{placeId}/refresh,
+// re-fetching a catalog entry from the places provider right away
+func (h *AdminHandler) RefreshCoffeeShop(w http.ResponseWriter, r *http.Request) {
+	placeID := r.PathValue("placeId")
+
+	shop, err := services.RefreshCoffeeShop(r.Context(), h.db, h.placesService, placeID)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "Refreshed catalog entry", "place_id", placeID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shop)
+}
+
+// SetReviewHidden handles PUT /admin/reviews/{reviewId}/hidden, which is
+// open to moderators as well as admins
+func (h *AdminHandler) SetReviewHidden(w http.ResponseWriter, r *http.Request) {
+	identity, ok := requireIdentity(w, r)
+	if !ok {
+		return
+	}
+
+	reviewID, err := utils.ParseInt(r.PathValue("reviewId"))
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest("Invalid review ID"))
+		return
+	}
+
+	var req models.ReviewVisibilityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.InfoContext(r.Context(), "Invalid request body", "err", err)
+		apierror.Write(w, r, apierror.BadRequest("Invalid request body"))
+		return
+	}
+
+	rowsAffected, err := h.db.SetReviewHidden(reviewID, identity.UserID, req.Hidden)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	if rowsAffected == 0 {
+		apierror.Write(w, r, apierror.NotFound(apierror.CodeReviewNotFound, "Review not found"))
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "Set review visibility", "moderator_id", identity.UserID, "review_id", reviewID, "hidden", req.Hidden)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Review visibility updated",
+	})
+}
+
+// adminUserID parses the {userId} path value
+func adminUserID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	userID, err := utils.ParseInt(r.PathValue("userId"))
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest("Invalid user ID"))
+		return 0, false
+	}
+	return userID, true
+}
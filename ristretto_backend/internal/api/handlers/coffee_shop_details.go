@@ -2,89 +2,101 @@ package handlers
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
+	"strings"
 
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/api/apierror"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/config"
 	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/db"
 	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
 	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/services"
-	"github.com/RobertGarabetian/ristretto/ristretto_backend/pkg/utils"
 )
 
 // CoffeeShopDetailsHandler handles requests for coffee shop details
 type CoffeeShopDetailsHandler struct {
-	db            *db.DB
-	placesService *services.PlacesService
+	db                *db.DB
+	placesService     services.PlacesProvider
+	search            config.SearchConfig
+	allowMockFallback bool
+	logger            *slog.Logger
 }
 
 // NewCoffeeShopDetailsHandler creates a new CoffeeShopDetailsHandler
-func NewCoffeeShopDetailsHandler(db *db.DB, placesService *services.PlacesService) *CoffeeShopDetailsHandler {
+func NewCoffeeShopDetailsHandler(db *db.DB, placesService services.PlacesProvider, search config.SearchConfig, allowMockFallback bool, logger *slog.Logger) *CoffeeShopDetailsHandler {
 	return &CoffeeShopDetailsHandler{
-		db:            db,
-		placesService: placesService,
+		db:                db,
+		placesService:     placesService,
+		search:            search,
+		allowMockFallback: allowMockFallback,
+		logger:            logger,
 	}
 }
 
-// HandleCoffeeShopDetails handles GET requests for a specific coffee shop's details
+// HandleCoffeeShopDetails handles GET /coffee_shops/{placeId}, returning a coffee shop's details
 func (h *CoffeeShopDetailsHandler) HandleCoffeeShopDetails(w http.ResponseWriter, r *http.Request) {
-	log.Printf("getCoffeeShopDetails handler called: %s %s", r.Method, r.URL.String())
-
-	if r.Method != http.MethodGet {
-		log.Printf("ERROR: Method not allowed: %s", r.Method)
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	// Get the authenticated user from the request context
+	identity, ok := requireIdentity(w, r)
+	if !ok {
 		return
 	}
+	userID := identity.UserID
 
-	// Get user ID from request header
-	userIDStr := r.Header.Get("X-User-ID")
-	log.Printf("X-User-ID header: %s", userIDStr)
-
-	userID, err := utils.ParseInt(userIDStr)
-	if err != nil {
-		log.Printf("ERROR: Invalid user ID: %s, error: %v", userIDStr, err)
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
-		return
-	}
-	log.Printf("User ID parsed: %d", userID)
-
-	// Extract place ID from URL path
-	// URL path format: /coffee_shops/{place_id}
-	placeID := r.URL.Path[len("/coffee_shops/"):]
+	placeID := r.PathValue("placeId")
 	if placeID == "" {
-		log.Printf("ERROR: Missing place ID in request")
-		http.Error(w, "Place ID is required", http.StatusBadRequest)
+		apierror.Write(w, r, apierror.BadRequest("Place ID is required"))
 		return
 	}
-	log.Printf("Place ID extracted: %s", placeID)
 
 	// Fetch coffee shop details from Google Places API
-	placeDetails, err := h.placesService.GetPlaceDetails(placeID)
+	placeDetails, err := h.placesService.GetPlaceDetails(placesContext(r), placeID)
+	source := ""
 	if err != nil {
-		log.Printf("ERROR: Failed to fetch coffee shop details: %v", err)
-
-		// Return mock data for development if Google Places API fails
-		if isDevEnvironment() {
-			log.Println("Development environment detected, returning mock details")
-			mockResponse := createMockCoffeeShopDetails(placeID)
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(mockResponse)
+		h.logger.WarnContext(r.Context(), "Failed to fetch coffee shop details", "place_id", placeID, "err", err)
+
+		// Serve the last synced catalog entry while the places provider is unavailable
+		var placesErr *services.PlacesAPIError
+		shop, catalogErr := h.db.GetCoffeeShop(placeID)
+		switch {
+		case errors.As(err, &placesErr) && placesErr.NotFound():
+			// The place no longer exists, so there is nothing to fall back to
+			apierror.Write(w, r, err)
+			return
+		case catalogErr == nil && shop.LastSyncedAt != nil:
+			h.logger.InfoContext(r.Context(), "Serving catalog entry", "place_id", placeID, "last_synced_at", shop.LastSyncedAt)
+			placeDetails = shop.PlaceDetails()
+			source = models.DataSourceCatalog
+			w.Header().Set("X-Data-Source", source)
+		case h.allowMockFallback:
+			// Return mock data outside production if the places provider fails
+			h.logger.InfoContext(r.Context(), "Mock fallback enabled, returning mock details")
+			writeMockResponse(w, createMockCoffeeShopDetails(placeID, h.search.DefaultLatitude, h.search.DefaultLongitude))
+			return
+		default:
+			apierror.Write(w, r, err)
 			return
 		}
-
-		http.Error(w, "Failed to fetch coffee shop details", http.StatusInternalServerError)
-		return
+	} else if err := h.db.FillCoffeeShop(models.NewCatalogCoffeeShop(placeDetails)); err != nil {
+		// Continue without updating the catalog rather than failing
+		h.logger.ErrorContext(r.Context(), "Failed to save coffee shop to catalog", "place_id", placeID, "err", err)
 	}
 
 	// Check if this coffee shop is in the user's favorites
 	favoriteIDs, err := h.db.GetUserFavorites(userID)
 	if err != nil {
-		log.Printf("Error fetching user favorites: %v", err)
+		h.logger.ErrorContext(r.Context(), "Failed to fetch user favorites", "err", err)
 		// Continue without favorites rather than failing
 		favoriteIDs = make(map[string]bool)
 	}
 
-	// Debug output
-	log.Printf("Place details received: %+v", placeDetails)
+	// Fetch our own aggregated rating for this coffee shop
+	rating, err := h.db.GetReviewAggregate(placeID)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to fetch review aggregate", "place_id", placeID, "err", err)
+		// Continue without a rating rather than failing
+		rating = nil
+	}
 
 	// Handle potentially nil values safely
 	var openingHours []string
@@ -92,16 +104,7 @@ func (h *CoffeeShopDetailsHandler) HandleCoffeeShopDetails(w http.ResponseWriter
 		openingHours = formatOpeningHours(placeDetails.CurrentOpeningHours)
 	}
 
-	var photos []string
-	if placeDetails.Photos != nil && len(placeDetails.Photos) > 0 {
-		photos = formatPhotos(placeDetails.Photos)
-	}
-
-	// Transform photo names into actual URLs
-	photoURLs := h.placesService.TransformPhotoURLs(photos, services.PhotoSize{
-		MaxWidthPx:  400,
-		MaxHeightPx: 300,
-	})
+	photos := h.formatPhotos(placeDetails.Photos, services.PhotoSizeSmall)
 
 	// Convert PlaceDetails to CoffeeShopDetails with null checks
 	coffeeShopDetails := models.CoffeeShopDetails{
@@ -113,26 +116,25 @@ func (h *CoffeeShopDetailsHandler) HandleCoffeeShopDetails(w http.ResponseWriter
 		PhoneNumber:  placeDetails.InternationalPhoneNumber,
 		Website:      placeDetails.WebsiteURI,
 		Rating:       placeDetails.Rating,
-		PriceLevel:   placeDetails.PriceLevel,
+		PriceLevel:   int(placeDetails.PriceLevel),
 		IsFavorite:   favoriteIDs[placeID],
 		OpeningHours: openingHours,
-		Photos:       photoURLs,
+		Photos:       photos,
+		Ristretto:    rating,
 	}
 
 	// Prepare our response
 	response := models.CoffeeShopDetailsResponse{
 		CoffeeShop: coffeeShopDetails,
+		Source:     source,
 	}
 
-	log.Printf("Sending response with coffee shop details for: %s", placeDetails.DisplayName.Text)
-
 	// Send response
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("ERROR: Failed to encode response: %v", err)
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-		return
+		// The status is already sent, so the error can only be logged
+		h.logger.ErrorContext(r.Context(), "Failed to encode response", "err", err)
 	}
 }
 
@@ -188,35 +190,73 @@ func formatOpeningHours(openingHours *models.OpeningHours) []string {
 	return formattedHours
 }
 
-// formatPhotos converts Google Places API photos to our format
-func formatPhotos(photos []*models.Photo) []string {
-	if photos == nil || len(photos) == 0 {
+// formatPhotos converts Google Places API photos to photo proxy URLs at a
+// PhotoSizes preset, keeping the author attributions Google requires us to show
+func (h *CoffeeShopDetailsHandler) formatPhotos(photos []*models.Photo, size string) []models.CoffeeShopPhoto {
+	if len(photos) == 0 {
 		return nil
 	}
 
-	photoUrls := make([]string, 0, len(photos))
+	formatted := make([]models.CoffeeShopPhoto, 0, len(photos))
 	for _, photo := range photos {
-		if photo != nil && photo.Name != "" {
-			// We don't actually construct URLs here since we'd need to make additional API calls
-			// In a real implementation, you might want to use the Places Photos API
-			// For now, we'll just store the photo reference which the frontend can use
-			photoUrls = append(photoUrls, photo.Name)
+		if photo == nil || photo.Name == "" {
+			continue
+		}
+		url := h.placesService.GetPhotoURL(photo.Name, size)
+		if url == "" {
+			continue
+		}
+
+		width, height := services.PhotoSizes[size].Fit(photo.WidthPx, photo.HeightPx)
+		formatted = append(formatted, models.CoffeeShopPhoto{
+			URL:          url,
+			Width:        width,
+			Height:       height,
+			Attributions: formatAttributions(photo.AuthorAttributions),
+		})
+	}
+
+	return formatted
+}
+
+// formatAttributions drops attributions without an author name and makes
+// Google's protocol-relative links absolute
+func formatAttributions(attributions []models.AuthorAttribution) []models.AuthorAttribution {
+	formatted := make([]models.AuthorAttribution, 0, len(attributions))
+	for _, attribution := range attributions {
+		if attribution.DisplayName == "" {
+			continue
 		}
+		attribution.URI = absoluteURL(attribution.URI)
+		attribution.PhotoURI = absoluteURL(attribution.PhotoURI)
+		formatted = append(formatted, attribution)
+	}
+	if len(formatted) == 0 {
+		return nil
 	}
+	return formatted
+}
 
-	return photoUrls
+// absoluteURL adds https to a protocol-relative URL such as "//maps.google.com/..."
+func absoluteURL(url string) string {
+	if strings.HasPrefix(url, "//") {
+		return "https:" + url
+	}
+	return url
 }
 
-// createMockCoffeeShopDetails creates mock coffee shop details for development
-func createMockCoffeeShopDetails(placeID string) models.CoffeeShopDetailsResponse {
+// createMockCoffeeShopDetails creates mock coffee shop details for development,
+// placed at the default search center. It has no photos, since the photo
+// proxy only serves real, signed Places photos.
+func createMockCoffeeShopDetails(placeID string, latitude, longitude float64) models.CoffeeShopDetailsResponse {
 	return models.CoffeeShopDetailsResponse{
 		CoffeeShop: models.CoffeeShopDetails{
 			ID:          placeID,
 			Name:        "Café Sunrise",
-			Latitude:    37.7937,
-			Longitude:   -122.3965,
-			Address:     "123 Coffee Street, San Francisco, CA 94107",
-			PhoneNumber: "+1 (415) 555-1234",
+			Latitude:    latitude,
+			Longitude:   longitude,
+			Address:     "123 Coffee Street, Los Angeles, CA 90012",
+			PhoneNumber: "+1 (213) 555-1234",
 			Website:     "https://example.com/coffee",
 			Rating:      4.5,
 			IsFavorite:  false,
@@ -230,10 +270,7 @@ func createMockCoffeeShopDetails(placeID string) models.CoffeeShopDetailsRespons
 				"Saturday: 8:00 - 20:00",
 				"Sunday: 8:00 - 18:00",
 			},
-			Photos: []string{
-				"https://places.googleapis.com/v1/places/mock_1/photos/1/media?key=mock_key&maxWidthPx=400&maxHeightPx=300",
-				"https://places.googleapis.com/v1/places/mock_1/photos/2/media?key=mock_key&maxWidthPx=400&maxHeightPx=300",
-			},
 		},
+		Source: models.DataSourceMock,
 	}
 }
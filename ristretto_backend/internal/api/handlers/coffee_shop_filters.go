@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/validation"
+)
+
+// Sort orders supported by the coffee shop list endpoint
+const (
+	sortByDistance       = "distance"
+	sortByRating         = "rating"
+	sortByRistrettoScore = "ristrettoScore"
+	sortByName           = "name"
+)
+
+const (
+	// maxSortedShops is the most matching shops gathered and sorted for sort
+	// orders other than distance. Shops further away are left out.
+	maxSortedShops = 60
+	// maxSortPages bounds the nearby search pages fetched to gather them
+	maxSortPages = 3
+)
+
+// coffeeShopListOptions holds the sort order and filters of a coffee shop list request
+type coffeeShopListOptions struct {
+	Sort          string
+	OpenNow       bool
+	MinRating     float64
+	PriceLevels   map[int]bool // Empty means any price level
+	FavoritesOnly bool
+	Visited       *bool // Nil means visited and unvisited shops
+}
+
+// parseCoffeeShopListOptions parses the sort and filter query parameters,
+// recording invalid ones on v. Only distance order is paged, so other sort
+// orders can't be combined with a cursor.
+func parseCoffeeShopListOptions(query url.Values, v *validation.Validator) coffeeShopListOptions {
+	opts := coffeeShopListOptions{
+		Sort:        sortByDistance,
+		PriceLevels: make(map[int]bool),
+	}
+
+	if sortBy := query.Get("sort"); sortBy != "" {
+		switch sortBy {
+		case sortByDistance, sortByRating, sortByRistrettoScore, sortByName:
+			opts.Sort = sortBy
+		default:
+			v.Add("sort", "must be one of distance, rating, ristrettoScore or name")
+		}
+	}
+	v.Check(opts.Sort == sortByDistance || query.Get("cursor") == "", "sort", "must be distance when a cursor is given")
+
+	if openNow := v.QueryBool(query, "openNow"); openNow != nil {
+		opts.OpenNow = *openNow
+	}
+	if favoritesOnly := v.QueryBool(query, "favoritesOnly"); favoritesOnly != nil {
+		opts.FavoritesOnly = *favoritesOnly
+	}
+	opts.Visited = v.QueryBool(query, "visited")
+	opts.MinRating = v.QueryFloat(query, "minRating", 0, 0, 5)
+
+	if priceLevels := query.Get("priceLevel"); priceLevels != "" {
+		for _, level := range strings.Split(priceLevels, ",") {
+			l, err := strconv.Atoi(strings.TrimSpace(level))
+			if err != nil || l < 0 || l > 4 {
+				v.Add("priceLevel", "must be a comma-separated list of levels between 0 and 4")
+				break
+			}
+			opts.PriceLevels[l] = true
+		}
+	}
+
+	return opts
+}
+
+// matches reports whether a coffee shop passes every filter
+func (opts coffeeShopListOptions) matches(shop models.CoffeeShop) bool {
+	if opts.OpenNow && (shop.OpenNow == nil || !*shop.OpenNow) {
+		return false
+	}
+	if opts.MinRating > 0 && shop.Rating < opts.MinRating {
+		return false
+	}
+	if len(opts.PriceLevels) > 0 && !opts.PriceLevels[shop.PriceLevel] {
+		return false
+	}
+	if opts.FavoritesOnly && !shop.IsFavorite {
+		return false
+	}
+	if opts.Visited != nil && shop.IsVisited != *opts.Visited {
+		return false
+	}
+	return true
+}
+
+// sortShops orders coffee shops that already passed the filters
+func (opts coffeeShopListOptions) sortShops(shops []models.CoffeeShop) {
+	sort.SliceStable(shops, func(i, j int) bool {
+		a, b := shops[i], shops[j]
+		switch opts.Sort {
+		case sortByRating:
+			return a.Rating > b.Rating
+		case sortByRistrettoScore:
+			// Shops without reviews sort last
+			return ristrettoScore(a) > ristrettoScore(b)
+		case sortByName:
+			return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+		default:
+			return a.Distance < b.Distance
+		}
+	})
+}
+
+// ristrettoScore returns a shop's Ristretto score, or -1 if it has no reviews
+func ristrettoScore(shop models.CoffeeShop) float64 {
+	if shop.RistrettoScore == nil {
+		return -1
+	}
+	return *shop.RistrettoScore
+}
@@ -1,147 +1,251 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/api/apierror"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/auth"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/config"
 	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/db"
 	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
 	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/services"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/validation"
 	"github.com/RobertGarabetian/ristretto/ristretto_backend/pkg/utils"
 )
 
 // CoffeeShopsHandler handles requests for coffee shops
 type CoffeeShopsHandler struct {
-	db            *db.DB
-	placesService *services.PlacesService
+	db                *db.DB
+	placesService     services.PlacesProvider
+	search            config.SearchConfig
+	allowMockFallback bool
+	logger            *slog.Logger
 }
 
 // NewCoffeeShopsHandler creates a new CoffeeShopsHandler
-func NewCoffeeShopsHandler(db *db.DB, placesService *services.PlacesService) *CoffeeShopsHandler {
+func NewCoffeeShopsHandler(db *db.DB, placesService services.PlacesProvider, search config.SearchConfig, allowMockFallback bool, logger *slog.Logger) *CoffeeShopsHandler {
 	return &CoffeeShopsHandler{
-		db:            db,
-		placesService: placesService,
+		db:                db,
+		placesService:     placesService,
+		search:            search,
+		allowMockFallback: allowMockFallback,
+		logger:            logger,
 	}
 }
 
-// HandleCoffeeShops handles GET requests for coffee shops
+// HandleCoffeeShops handles GET /coffee_shops, returning coffee shops near a
+// location. Filters are applied before each page is filled. Pages follow
+// distance order; other sorts are applied across the nearest maxSortedShops
+// matching shops and return a single page, so a cursor can't be combined
+// with them.
 func (h *CoffeeShopsHandler) HandleCoffeeShops(w http.ResponseWriter, r *http.Request) {
-	log.Printf("getCoffeeShops handler called: %s %s", r.Method, r.URL.String())
-
-	if r.Method != http.MethodGet {
-		log.Printf("ERROR: Method not allowed: %s", r.Method)
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	// Get the authenticated user from the request context
+	identity, ok := requireIdentity(w, r)
+	if !ok {
 		return
 	}
+	userID := identity.UserID
 
-	// Get user ID from request header
-	userIDStr := r.Header.Get("X-User-ID")
-	log.Printf("X-User-ID header: %s", userIDStr)
+	// Parse and validate query parameters, reporting every invalid field at once
+	query := r.URL.Query()
 
-	userID, err := utils.ParseInt(userIDStr)
-	if err != nil {
-		log.Printf("ERROR: Invalid user ID: %s, error: %v", userIDStr, err)
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+	v := validation.New()
+	latitude := v.QueryFloat(query, "lat", h.search.DefaultLatitude, -90, 90)
+	longitude := v.QueryFloat(query, "lng", h.search.DefaultLongitude, -180, 180)
+	radius := v.QueryFloat(query, "radius", h.search.DefaultRadius, h.search.MinRadius, h.search.MaxRadius)
+	maxResults := v.QueryInt(query, "max", h.search.DefaultMaxResults, 1, h.search.MaxResults)
+	listOptions := parseCoffeeShopListOptions(query, v)
+	if err := v.Err(); err != nil {
+		h.logger.InfoContext(r.Context(), "Invalid query parameters", "err", err)
+		apierror.Write(w, r, err)
 		return
 	}
-	log.Printf("User ID parsed: %d", userID)
-
-	// Parse query parameters
-	latitude := 37.7937 // Default values
-	longitude := -122.3965
-	radius := 500.0
-	maxResults := 10
 
-	// Log query parameters
-	log.Printf("Query parameters: %v", r.URL.Query())
-
-	// Extract from query params if provided
-	if lat := r.URL.Query().Get("lat"); lat != "" {
-		if parsedLat, err := strconv.ParseFloat(lat, 64); err == nil {
-			latitude = parsedLat
-			log.Printf("Using provided latitude: %f", latitude)
+	// A cursor from a previous page fixes the search circle
+	cursor := services.NearbyCursor{Latitude: latitude, Longitude: longitude, Radius: radius}
+	if c := r.URL.Query().Get("cursor"); c != "" {
+		var err error
+		cursor, err = services.DecodeNearbyCursor(c)
+		if err != nil {
+			h.logger.InfoContext(r.Context(), "Invalid cursor", "err", err)
+			apierror.Write(w, r, apierror.BadRequest("Invalid cursor"))
+			return
 		}
-	}
-	if lng := r.URL.Query().Get("lng"); lng != "" {
-		if parsedLng, err := strconv.ParseFloat(lng, 64); err == nil {
-			longitude = parsedLng
-			log.Printf("Using provided longitude: %f", longitude)
+		// Cursors are client-supplied, so they get the same bounds as the query
+		if err := h.validateCursor(cursor); err != nil {
+			h.logger.InfoContext(r.Context(), "Cursor out of bounds", "err", err)
+			apierror.Write(w, r, err)
+			return
 		}
+		latitude, longitude = cursor.Latitude, cursor.Longitude
 	}
-	if rad := r.URL.Query().Get("radius"); rad != "" {
-		if parsedRad, err := strconv.ParseFloat(rad, 64); err == nil {
-			radius = parsedRad
-			log.Printf("Using provided radius: %f", radius)
-		}
+
+	// Fetch user's favorites
+	favoriteIDs, err := h.db.GetUserFavorites(userID)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to fetch user favorites", "err", err)
+		// Continue without favorites rather than failing
+		favoriteIDs = make(map[string]bool)
 	}
-	if max := r.URL.Query().Get("max"); max != "" {
-		if parsedMax, err := strconv.Atoi(max); err == nil {
-			maxResults = parsedMax
-			log.Printf("Using provided max results: %d", maxResults)
-		}
+
+	// Fetch the coffee shops the user has visited
+	visitedIDs, err := h.db.GetUserVisitedPlaces(userID)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to fetch user visits", "err", err)
+		// Continue without visits rather than failing
+		visitedIDs = make(map[string]bool)
 	}
 
-	// Fetch coffee shops from Google Places API
-	places, err := h.placesService.SearchNearby(latitude, longitude, radius, maxResults)
+	// Fetch a page of coffee shops from the places provider, filtering while
+	// the page fills so filters don't leave it short. Other sorts need more
+	// than one page of candidates to sort.
+	include := func(place models.Place) bool {
+		return listOptions.matches(newCoffeeShop(place, latitude, longitude, favoriteIDs, visitedIDs))
+	}
+	var page services.NearbyPage
+	if listOptions.Sort == sortByDistance {
+		page, err = services.SearchNearbyPage(placesContext(r), h.placesService, cursor, maxResults, include)
+	} else {
+		page.Places, err = gatherNearby(placesContext(r), h.placesService, cursor, maxSortedShops, include)
+	}
+	places := page.Places
 	if err != nil {
-		log.Printf("ERROR: Failed to fetch coffee shops: %v", err)
-
-		// Return mock data for development if Google Places API fails
-		if isDevEnvironment() {
-			log.Println("Development environment detected, returning mock data")
-			mockResponse := createMockResponse(latitude, longitude)
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(mockResponse)
+		h.logger.WarnContext(r.Context(), "Failed to fetch coffee shops", "err", err)
+
+		// Return mock data outside production if the places provider fails
+		if h.allowMockFallback {
+			h.logger.InfoContext(r.Context(), "Mock fallback enabled, returning mock data")
+			writeMockResponse(w, createMockResponse(latitude, longitude))
 			return
 		}
 
-		http.Error(w, "Failed to fetch coffee shops", http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
-	// Fetch user's favorites
-	favoriteIDs, err := h.db.GetUserFavorites(userID)
+	// Fetch Ristretto ratings for all places in a single query
+	placeIDs := make([]string, 0, len(places))
+	for _, place := range places {
+		placeIDs = append(placeIDs, place.PlaceID)
+	}
+	ratings, err := h.db.GetReviewAggregates(placeIDs)
 	if err != nil {
-		log.Printf("Error fetching user favorites: %v", err)
-		// Continue without favorites rather than failing
-		favoriteIDs = make(map[string]bool)
+		h.logger.ErrorContext(r.Context(), "Failed to fetch review aggregates", "err", err)
+		// Continue without ratings rather than failing
+		ratings = make(map[string]models.RistrettoRating)
 	}
 
 	// Extract coffee shop data
 	var coffeeShops []models.CoffeeShop
 	for _, place := range places {
-		coffeeShop := models.CoffeeShop{
-			ID:         place.PlaceID,
-			Name:       place.DisplayName.Text,
-			Latitude:   place.Location.Latitude,
-			Longitude:  place.Location.Longitude,
-			IsFavorite: favoriteIDs[place.PlaceID],
+		coffeeShop := newCoffeeShop(place, latitude, longitude, favoriteIDs, visitedIDs)
+		if rating, ok := ratings[place.PlaceID]; ok {
+			score := rating.Score
+			coffeeShop.RistrettoScore = &score
+			coffeeShop.ReviewCount = rating.ReviewCount
 		}
 		coffeeShops = append(coffeeShops, coffeeShop)
 	}
 
-	// Prepare our response
-	response := models.CoffeeShopsResponse{
-		CoffeeShops: coffeeShops,
+	// Prepare our response. Only distance order continues onto later pages;
+	// other sorts return the top of everything gathered.
+	response := models.CoffeeShopsResponse{NextCursor: page.NextCursor}
+	listOptions.sortShops(coffeeShops)
+	if listOptions.Sort != sortByDistance {
+		response.SortedWithin = len(coffeeShops)
+		if len(coffeeShops) > maxResults {
+			coffeeShops = coffeeShops[:maxResults]
+		}
 	}
+	response.CoffeeShops = coffeeShops
 
-	log.Printf("Sending response with %d coffee shops", len(coffeeShops))
+	h.logger.DebugContext(r.Context(), "Fetched coffee shops", "count", len(coffeeShops))
 
 	// Send response
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("ERROR: Failed to encode response: %v", err)
+		h.logger.ErrorContext(r.Context(), "Failed to encode response", "err", err)
+	}
+}
+
+// newCoffeeShop builds the coffee shop for a place, without its Ristretto rating
+func newCoffeeShop(place models.Place, latitude, longitude float64, favoriteIDs, visitedIDs map[string]bool) models.CoffeeShop {
+	coffeeShop := models.CoffeeShop{
+		ID:         place.PlaceID,
+		Name:       place.DisplayName.Text,
+		Latitude:   place.Location.Latitude,
+		Longitude:  place.Location.Longitude,
+		IsFavorite: favoriteIDs[place.PlaceID],
+		IsVisited:  visitedIDs[place.PlaceID],
+		Distance:   math.Round(utils.DistanceMeters(latitude, longitude, place.Location.Latitude, place.Location.Longitude)),
+		Rating:     place.Rating,
+		PriceLevel: int(place.PriceLevel),
+	}
+	if place.CurrentOpeningHours != nil {
+		openNow := place.CurrentOpeningHours.OpenNow
+		coffeeShop.OpenNow = &openNow
+	}
+	return coffeeShop
+}
+
+// gatherNearby collects up to limit places that pass include, in the order
+// SearchNearbyPage returns them, following page cursors for at most
+// maxSortPages pages
+func gatherNearby(ctx context.Context, provider services.PlacesProvider, cursor services.NearbyCursor, limit int, include func(models.Place) bool) ([]models.Place, error) {
+	var places []models.Place
+	for pages := 0; pages < maxSortPages && len(places) < limit; pages++ {
+		page, err := services.SearchNearbyPage(ctx, provider, cursor, limit-len(places), include)
+		if err != nil {
+			return nil, err
+		}
+		places = append(places, page.Places...)
+
+		if page.NextCursor == "" {
+			break
+		}
+		if cursor, err = services.DecodeNearbyCursor(page.NextCursor); err != nil {
+			return nil, err
+		}
 	}
+	return places, nil
+}
+
+// validateCursor checks a decoded cursor's search circle against the same
+// bounds as the lat, lng and radius query parameters
+func (h *CoffeeShopsHandler) validateCursor(cursor services.NearbyCursor) error {
+	v := validation.New()
+	v.Latitude("cursor.lat", cursor.Latitude)
+	v.Longitude("cursor.lng", cursor.Longitude)
+	v.Check(cursor.Radius >= h.search.MinRadius && cursor.Radius <= h.search.MaxRadius, "cursor.radius",
+		"must be between "+strconv.FormatFloat(h.search.MinRadius, 'f', -1, 64)+" and "+strconv.FormatFloat(h.search.MaxRadius, 'f', -1, 64))
+	return v.Err()
 }
 
-// isDevEnvironment returns true if we're running in a development environment
-func isDevEnvironment() bool {
-	// In a real application, you would check an environment variable
-	// For now, we'll just return true to enable mock data
-	return true
+// placesContext returns the context for places provider calls. Admins can
+// bypass the places cache by sending "Cache-Control: no-cache"; the header is
+// ignored for everyone else, since each bypass is a billed Places call.
+func placesContext(r *http.Request) context.Context {
+	if !strings.Contains(r.Header.Get("Cache-Control"), "no-cache") {
+		return r.Context()
+	}
+	if identity, ok := auth.IdentityFromContext(r.Context()); !ok || !identity.HasRole(auth.RoleAdmin) {
+		return r.Context()
+	}
+	return services.WithCacheBypass(r.Context())
+}
+
+// writeMockResponse sends mock data flagged so clients can't mistake it for real data
+func writeMockResponse(w http.ResponseWriter, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Data-Source", models.DataSourceMock)
+	json.NewEncoder(w).Encode(response)
 }
 
 // createMockResponse creates a mock response for development
@@ -170,5 +274,6 @@ func createMockResponse(latitude, longitude float64) models.CoffeeShopsResponse
 				IsFavorite: true,
 			},
 		},
+		Source: models.DataSourceMock,
 	}
 }
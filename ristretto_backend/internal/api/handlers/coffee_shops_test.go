@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/auth"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/config"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/services"
+)
+
+// testSearchConfig holds the search bounds used by coffee shop handler tests
+var testSearchConfig = config.SearchConfig{
+	DefaultLatitude:   34,
+	DefaultLongitude:  -118,
+	DefaultRadius:     1000,
+	MinRadius:         100,
+	MaxRadius:         5000,
+	DefaultMaxResults: 10,
+	MaxResults:        60,
+}
+
+// lineProvider returns a fixture provider with count cafes in a line north
+// of (34, -118), about 55 meters apart, rated higher the further away they are
+func lineProvider(t *testing.T, count int) services.PlacesProvider {
+	t.Helper()
+
+	features := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		features = append(features, fmt.Sprintf(
+			`{"geometry": {"type": "Point", "coordinates": [-118, %f]}, "properties": {"id": "cafe_%d", "name": "Cafe %d", "rating": %f}}`,
+			34+float64(i+1)*0.0005, i, i, 1+4*float64(i)/float64(count)))
+	}
+	path := filepath.Join(t.TempDir(), "places.json")
+	contents := `{"type": "FeatureCollection", "features": [` + strings.Join(features, ",") + `]}`
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	provider, err := services.NewFixturePlacesProvider(path)
+	if err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+	return provider
+}
+
+func TestHandleCoffeeShopsRejectsCursorWithOtherSorts(t *testing.T) {
+	h := NewCoffeeShopsHandler(nil, nil, testSearchConfig, false, discardLogger)
+	cursor := services.NearbyCursor{Latitude: 34, Longitude: -118, Radius: 1000}.Encode()
+
+	req := httptest.NewRequest(http.MethodGet, "/coffee_shops?sort=rating&cursor="+cursor, nil)
+	req = req.WithContext(auth.WithIdentity(req.Context(), &auth.Identity{UserID: 1}))
+	rec := httptest.NewRecorder()
+	h.HandleCoffeeShops(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	var body struct {
+		Error struct {
+			Fields []struct {
+				Field string `json:"field"`
+			} `json:"fields"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(body.Error.Fields) != 1 || body.Error.Fields[0].Field != "sort" {
+		t.Errorf("fields = %+v, want only sort", body.Error.Fields)
+	}
+}
+
+func TestGatherNearbySortsBeyondTheFirstPage(t *testing.T) {
+	provider := lineProvider(t, 25)
+	cursor := services.NearbyCursor{Latitude: 34, Longitude: -118, Radius: 3000}
+
+	places, err := gatherNearby(context.Background(), provider, cursor, maxSortedShops, nil)
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	if len(places) != 25 {
+		t.Fatalf("gathered %d places, want 25", len(places))
+	}
+
+	// The best rated cafe is the furthest away, past what one search returns
+	shops := make([]models.CoffeeShop, 0, len(places))
+	for _, place := range places {
+		shops = append(shops, newCoffeeShop(place, 34, -118, nil, nil))
+	}
+	coffeeShopListOptions{Sort: sortByRating}.sortShops(shops)
+	if shops[0].ID != "cafe_24" {
+		t.Errorf("best rated shop = %s, want cafe_24", shops[0].ID)
+	}
+}
+
+func TestGatherNearbyStopsAtLimit(t *testing.T) {
+	provider := lineProvider(t, 30)
+	cursor := services.NearbyCursor{Latitude: 34, Longitude: -118, Radius: 3000}
+
+	places, err := gatherNearby(context.Background(), provider, cursor, 12, nil)
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	if len(places) != 12 {
+		t.Errorf("gathered %d places, want 12", len(places))
+	}
+}
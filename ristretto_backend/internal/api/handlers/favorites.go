@@ -2,58 +2,50 @@ package handlers
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
 
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/api/apierror"
 	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/db"
 	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
-	"github.com/RobertGarabetian/ristretto/ristretto_backend/pkg/utils"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/services"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/validation"
+)
+
+// Length limits for coffee shops sent in request bodies
+const (
+	maxPlaceIDLength        = 256
+	maxCoffeeShopNameLength = 200
 )
 
 // FavoritesHandler handles requests for favorites
 type FavoritesHandler struct {
-	db *db.DB
+	db            *db.DB
+	placesService services.PlacesProvider
+	logger        *slog.Logger
 }
 
 // NewFavoritesHandler creates a new FavoritesHandler
-func NewFavoritesHandler(db *db.DB) *FavoritesHandler {
+func NewFavoritesHandler(db *db.DB, placesService services.PlacesProvider, logger *slog.Logger) *FavoritesHandler {
 	return &FavoritesHandler{
-		db: db,
+		db:            db,
+		placesService: placesService,
+		logger:        logger,
 	}
 }
 
-// HandleFavorites handles requests for favorites
-func (h *FavoritesHandler) HandleFavorites(w http.ResponseWriter, r *http.Request) {
-	// Get user ID from request header
-	userIDStr := r.Header.Get("X-User-ID")
-	userID, err := utils.ParseInt(userIDStr)
-	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+// GetFavorites handles GET /favorites, returning the user's favorite coffee shops
+func (h *FavoritesHandler) GetFavorites(w http.ResponseWriter, r *http.Request) {
+	// Get the authenticated user from the request context
+	identity, ok := requireIdentity(w, r)
+	if !ok {
 		return
 	}
-
-	log.Printf("Handling favorites request: %s for user ID: %d", r.Method, userID)
-
-	switch r.Method {
-	case http.MethodGet:
-		h.getFavorites(w, r, userID)
-	case http.MethodPost:
-		h.addFavorite(w, r, userID)
-	case http.MethodDelete:
-		h.removeFavorite(w, r, userID)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
-}
-
-// getFavorites gets a user's favorite coffee shops
-func (h *FavoritesHandler) getFavorites(w http.ResponseWriter, r *http.Request, userID int) {
-	log.Printf("Getting favorites for user ID: %d", userID)
+	userID := identity.UserID
 
 	favorites, err := h.db.GetFavorites(userID)
 	if err != nil {
-		log.Printf("Database error fetching favorites: %v", err)
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -69,7 +61,7 @@ func (h *FavoritesHandler) getFavorites(w http.ResponseWriter, r *http.Request,
 		})
 	}
 
-	log.Printf("Found %d favorites for user ID: %d", len(coffeeShops), userID)
+	h.logger.DebugContext(r.Context(), "Fetched favorites", "user_id", userID, "count", len(coffeeShops))
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -77,33 +69,43 @@ func (h *FavoritesHandler) getFavorites(w http.ResponseWriter, r *http.Request,
 	})
 }
 
-// addFavorite adds a coffee shop to favorites
-func (h *FavoritesHandler) addFavorite(w http.ResponseWriter, r *http.Request, userID int) {
+// AddFavorite handles POST /favorites, adding a coffee shop to favorites
+func (h *FavoritesHandler) AddFavorite(w http.ResponseWriter, r *http.Request) {
+	// Get the authenticated user from the request context
+	identity, ok := requireIdentity(w, r)
+	if !ok {
+		return
+	}
+	userID := identity.UserID
+
 	var coffeeShop models.CoffeeShop
 
 	if err := json.NewDecoder(r.Body).Decode(&coffeeShop); err != nil {
-		log.Printf("Invalid request body: %v", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		h.logger.InfoContext(r.Context(), "Invalid request body", "err", err)
+		apierror.Write(w, r, apierror.BadRequest("Invalid request body"))
 		return
 	}
 
-	if coffeeShop.ID == "" || coffeeShop.Name == "" {
-		log.Printf("Missing required fields: ID or Name")
-		http.Error(w, "Place ID and Name are required", http.StatusBadRequest)
+	if err := validateCoffeeShopBody(coffeeShop, true); err != nil {
+		h.logger.InfoContext(r.Context(), "Invalid coffee shop", "err", err)
+		apierror.Write(w, r, err)
 		return
 	}
 
-	log.Printf("Adding coffee shop %s (%s) to favorites for user ID: %d",
-		coffeeShop.Name, coffeeShop.ID, userID)
+	// Only attach user data to places that exist
+	if err := services.ValidatePlace(r.Context(), h.db, h.placesService, coffeeShop.ID, h.logger); err != nil {
+		h.logger.InfoContext(r.Context(), "Rejected unknown place", "place_id", coffeeShop.ID, "err", err)
+		apierror.Write(w, r, err)
+		return
+	}
 
 	err := h.db.AddFavorite(userID, coffeeShop.ID, coffeeShop.Name, coffeeShop.Latitude, coffeeShop.Longitude)
 	if err != nil {
-		log.Printf("Database error adding favorite: %v", err)
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
-	log.Printf("Successfully added coffee shop to favorites")
+	h.logger.InfoContext(r.Context(), "Added favorite", "user_id", userID, "place_id", coffeeShop.ID)
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -111,34 +113,49 @@ func (h *FavoritesHandler) addFavorite(w http.ResponseWriter, r *http.Request, u
 	})
 }
 
-// removeFavorite removes a coffee shop from favorites
-func (h *FavoritesHandler) removeFavorite(w http.ResponseWriter, r *http.Request, userID int) {
+// RemoveFavorite handles DELETE /favorites?placeId=, removing a coffee shop from favorites
+func (h *FavoritesHandler) RemoveFavorite(w http.ResponseWriter, r *http.Request) {
+	// Get the authenticated user from the request context
+	identity, ok := requireIdentity(w, r)
+	if !ok {
+		return
+	}
+	userID := identity.UserID
+
 	placeID := r.URL.Query().Get("placeId")
 	if placeID == "" {
-		log.Printf("Missing required parameter: placeId")
-		http.Error(w, "Place ID is required", http.StatusBadRequest)
+		apierror.Write(w, r, apierror.BadRequest("Place ID is required"))
 		return
 	}
 
-	log.Printf("Removing coffee shop %s from favorites for user ID: %d", placeID, userID)
-
 	rowsAffected, err := h.db.RemoveFavorite(userID, placeID)
 	if err != nil {
-		log.Printf("Database error removing favorite: %v", err)
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
 	if rowsAffected == 0 {
-		log.Printf("Favorite not found: user ID %d, place ID %s", userID, placeID)
-		http.Error(w, "Favorite not found", http.StatusNotFound)
+		apierror.Write(w, r, apierror.NotFound(apierror.CodeFavoriteNotFound, "Favorite not found"))
 		return
 	}
 
-	log.Printf("Successfully removed coffee shop from favorites")
+	h.logger.InfoContext(r.Context(), "Removed favorite", "user_id", userID, "place_id", placeID)
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"message": "Removed from favorites",
 	})
 }
+
+// validateCoffeeShopBody checks a coffee shop sent in a favorites or visits
+// request body, including its location when it will be stored
+func validateCoffeeShopBody(shop models.CoffeeShop, withLocation bool) error {
+	v := validation.New()
+	v.Required("id", shop.ID, maxPlaceIDLength)
+	v.Required("name", shop.Name, maxCoffeeShopNameLength)
+	if withLocation {
+		v.Latitude("latitude", shop.Latitude)
+		v.Longitude("longitude", shop.Longitude)
+	}
+	return v.Err()
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/db"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/services"
+)
+
+// Readiness check results
+const (
+	checkOK          = "ok"
+	checkUnavailable = "unavailable"
+	checkDraining    = "draining"
+)
+
+// HealthHandler serves liveness and readiness probes
+type HealthHandler struct {
+	db       *db.DB
+	places   services.Pinger // Nil skips the places check
+	timeout  time.Duration
+	draining atomic.Bool
+	logger   *slog.Logger
+}
+
+// NewHealthHandler creates a new HealthHandler. Readiness also requires the
+// places provider to be reachable when checkPlaces is set and the provider
+// supports it.
+func NewHealthHandler(db *db.DB, placesService services.PlacesProvider, checkPlaces bool, timeout time.Duration, logger *slog.Logger) *HealthHandler {
+	h := &HealthHandler{
+		db:      db,
+		timeout: timeout,
+		logger:  logger,
+	}
+	if pinger, ok := placesService.(services.Pinger); ok && checkPlaces {
+		h.places = pinger
+	}
+	return h
+}
+
+// SetDraining makes readiness fail so load balancers stop sending traffic
+// while the server shuts down
+func (h *HealthHandler) SetDraining() {
+	h.draining.Store(true)
+}
+
+// Livez handles GET /livez, reporting that the process is up. It checks no
+// dependencies, so a database outage never gets the server restarted.
+func (h *HealthHandler) Livez(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
+
+// readiness is the JSON body of a readiness response
+type readiness struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// Readyz handles GET /readyz, reporting whether the server can serve
+// traffic. It returns 503 if any dependency is unavailable or the server is
+// shutting down.
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+
+	result := readiness{Status: checkOK, Checks: make(map[string]string)}
+	check := func(name string, err error) {
+		if err != nil {
+			h.logger.WarnContext(ctx, "Readiness check failed", "check", name, "err", err)
+			result.Checks[name] = checkUnavailable
+			result.Status = checkUnavailable
+			return
+		}
+		result.Checks[name] = checkOK
+	}
+
+	check("database", h.db.PingContext(ctx))
+	if h.places != nil {
+		check("places", h.places.Ping(ctx))
+	}
+	if h.draining.Load() {
+		result.Status = checkDraining
+	}
+
+	status := http.StatusOK
+	if result.Status != checkOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/api/apierror"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/auth"
+)
+
+// requireIdentity returns the authenticated user for a request. Requests that
+// did not pass through the auth middleware are rejected with 401.
+func requireIdentity(w http.ResponseWriter, r *http.Request) (*auth.Identity, bool) {
+	identity, ok := auth.IdentityFromContext(r.Context())
+	if !ok {
+		slog.ErrorContext(r.Context(), "No authenticated user", "method", r.Method, "path", r.URL.Path)
+		apierror.Write(w, r, apierror.Unauthenticated("Unauthorized"))
+		return nil, false
+	}
+	return identity, true
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/api/apierror"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/services"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/validation"
+)
+
+// PhotosHandler proxies Google Places photos so the API key stays on the server
+type PhotosHandler struct {
+	photos *services.PhotoCache
+	signer *services.PhotoSigner
+	maxAge time.Duration
+	logger *slog.Logger
+}
+
+// NewPhotosHandler creates a new PhotosHandler. Only URLs signed by signer
+// are served, and clients may cache photos for maxAge.
+func NewPhotosHandler(photos *services.PhotoCache, signer *services.PhotoSigner, maxAge time.Duration, logger *slog.Logger) *PhotosHandler {
+	return &PhotosHandler{
+		photos: photos,
+		signer: signer,
+		maxAge: maxAge,
+		logger: logger,
+	}
+}
+
+// HandlePhoto handles GET /photos/{name...}?size=&sig=, streaming a photo at
+// one of the size presets. The signature comes from the photo URLs in coffee
+// shop details. Responses carry an ETag, so revalidation is answered with
+// 304 Not Modified.
+func (h *PhotosHandler) HandlePhoto(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	size := r.URL.Query().Get("size")
+	if size == "" {
+		size = services.PhotoSizeSmall
+	}
+
+	v := validation.New()
+	v.Check(services.ValidPhotoName(name), "name", "must be a Google Places photo name")
+	_, ok := services.PhotoSizes[size]
+	v.Check(ok, "size", "must be one of "+strings.Join(photoSizeNames(), ", "))
+	if err := v.Err(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	// Unsigned names would let anyone spend the Places budget on photos
+	if !h.signer.Verify(name, size, r.URL.Query().Get("sig")) {
+		h.logger.InfoContext(r.Context(), "Rejected photo URL with an invalid signature", "photo_name", name, "size", size)
+		apierror.Write(w, r, apierror.Forbidden("Invalid photo signature"))
+		return
+	}
+
+	photo, err := h.photos.GetPhoto(r.Context(), name, size)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to fetch photo", "photo_name", name, "size", size, "err", err)
+		apierror.Write(w, r, err)
+		return
+	}
+
+	sum := sha256.Sum256(photo.Data)
+	w.Header().Set("Content-Type", photo.ContentType)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(h.maxAge.Seconds())))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	// ServeContent answers If-None-Match and Range requests
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(photo.Data))
+}
+
+// photoSizeNames returns the size presets in a stable order
+func photoSizeNames() []string {
+	names := make([]string, 0, len(services.PhotoSizes))
+	for name := range services.PhotoSizes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
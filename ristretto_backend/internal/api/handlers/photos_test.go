@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/services"
+)
+
+// discardLogger drops log output in tests
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// countingFetcher serves a placeholder image and counts fetches
+type countingFetcher struct {
+	fetches atomic.Int32
+}
+
+// FetchPhoto implements services.PhotoFetcher
+func (f *countingFetcher) FetchPhoto(ctx context.Context, photoName string, size services.PhotoSize) (*services.PhotoMedia, error) {
+	f.fetches.Add(1)
+	return &services.PhotoMedia{ContentType: "image/jpeg", Data: []byte("jpeg")}, nil
+}
+
+func TestHandlePhotoRequiresSignature(t *testing.T) {
+	signer := services.NewPhotoSigner([]byte("test key"))
+	places := services.NewPlacesService(services.PlacesServiceOptions{PhotoSigner: signer, Logger: discardLogger})
+	const name = "places/abc/photos/def"
+
+	// URLs handed out in coffee shop details are signed for their size
+	signed, err := url.Parse(places.GetPhotoURL(name, services.PhotoSizeSmall))
+	if err != nil {
+		t.Fatalf("parse photo URL: %v", err)
+	}
+	resized := signed.Query()
+	resized.Set("size", services.PhotoSizeLarge)
+
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+	}{
+		{name: "signed", target: signed.String(), wantStatus: http.StatusOK},
+		{name: "unsigned", target: "/photos/" + name + "?size=" + services.PhotoSizeSmall, wantStatus: http.StatusForbidden},
+		{name: "different size", target: signed.Path + "?" + resized.Encode(), wantStatus: http.StatusForbidden},
+		{name: "different photo", target: strings.Replace(signed.String(), "def", "xyz", 1), wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher := &countingFetcher{}
+			handler := NewPhotosHandler(services.NewPhotoCache(fetcher, nil, time.Hour, discardLogger), signer, time.Hour, discardLogger)
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /photos/{name...}", handler.HandlePhoto)
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK && fetcher.fetches.Load() != 0 {
+				t.Error("rejected request fetched a photo")
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/api/apierror"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/db"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/services"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/validation"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/pkg/utils"
+)
+
+// maxReviewBodyLength is the maximum number of characters allowed in a review body
+const maxReviewBodyLength = 5000
+
+// ReviewsHandler handles requests for coffee shop reviews
+type ReviewsHandler struct {
+	db            *db.DB
+	placesService services.PlacesProvider
+	logger        *slog.Logger
+}
+
+// NewReviewsHandler creates a new ReviewsHandler
+func NewReviewsHandler(db *db.DB, placesService services.PlacesProvider, logger *slog.Logger) *ReviewsHandler {
+	return &ReviewsHandler{
+		db:            db,
+		placesService: placesService,
+		logger:        logger,
+	}
+}
+
+// GetReviews handles GET /coffee_shops/{placeId}/reviews, returning all
+// reviews for a coffee shop
+func (h *ReviewsHandler) GetReviews(w http.ResponseWriter, r *http.Request) {
+	placeID := r.PathValue("placeId")
+
+	reviews, err := h.db.GetReviews(placeID)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	h.logger.DebugContext(r.Context(), "Fetched reviews", "place_id", placeID, "count", len(reviews))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.ReviewsResponse{
+		Reviews: reviews,
+	})
+}
+
+// CreateReview handles POST /coffee_shops/{placeId}/reviews, creating a
+// review for a coffee shop
+func (h *ReviewsHandler) CreateReview(w http.ResponseWriter, r *http.Request) {
+	// Get the authenticated user from the request context
+	identity, ok := requireIdentity(w, r)
+	if !ok {
+		return
+	}
+	userID := identity.UserID
+	placeID := r.PathValue("placeId")
+
+	req, ok := h.decodeReviewRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Only attach user data to places that exist
+	if err := services.ValidatePlace(r.Context(), h.db, h.placesService, placeID, h.logger); err != nil {
+		h.logger.InfoContext(r.Context(), "Rejected unknown place", "place_id", placeID, "err", err)
+		apierror.Write(w, r, err)
+		return
+	}
+
+	reviewID, err := h.db.CreateReview(userID, placeID, req)
+	if err == db.ErrDuplicateReview {
+		apierror.Write(w, r, apierror.New(http.StatusConflict, apierror.CodeAlreadyReviewed, "You have already reviewed this coffee shop"))
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	review, err := h.db.GetReview(reviewID)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "Created review", "review_id", reviewID, "place_id", placeID, "user_id", userID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(review)
+}
+
+// UpdateReview handles PUT /coffee_shops/{placeId}/reviews/{reviewId},
+// editing a review written by the user
+func (h *ReviewsHandler) UpdateReview(w http.ResponseWriter, r *http.Request) {
+	// Get the authenticated user from the request context
+	identity, ok := requireIdentity(w, r)
+	if !ok {
+		return
+	}
+	userID := identity.UserID
+	placeID := r.PathValue("placeId")
+
+	reviewID, err := utils.ParseInt(r.PathValue("reviewId"))
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest("Invalid review ID"))
+		return
+	}
+
+	if !h.authorizeReviewAuthor(w, r, userID, placeID, reviewID) {
+		return
+	}
+
+	req, ok := h.decodeReviewRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := h.db.UpdateReview(reviewID, userID, req); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	review, err := h.db.GetReview(reviewID)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "Updated review", "review_id", reviewID, "user_id", userID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(review)
+}
+
+// DeleteReview handles DELETE /coffee_shops/{placeId}/reviews/{reviewId},
+// deleting a review written by the user
+func (h *ReviewsHandler) DeleteReview(w http.ResponseWriter, r *http.Request) {
+	// Get the authenticated user from the request context
+	identity, ok := requireIdentity(w, r)
+	if !ok {
+		return
+	}
+	userID := identity.UserID
+	placeID := r.PathValue("placeId")
+
+	reviewID, err := utils.ParseInt(r.PathValue("reviewId"))
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest("Invalid review ID"))
+		return
+	}
+
+	if !h.authorizeReviewAuthor(w, r, userID, placeID, reviewID) {
+		return
+	}
+
+	if _, err := h.db.DeleteReview(reviewID, userID); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "Deleted review", "review_id", reviewID, "user_id", userID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Review deleted",
+	})
+}
+
+// authorizeReviewAuthor checks that the review exists for the place and was written by the user
+func (h *ReviewsHandler) authorizeReviewAuthor(w http.ResponseWriter, r *http.Request, userID int, placeID string, reviewID int) bool {
+	review, err := h.db.GetReview(reviewID)
+	if err == sql.ErrNoRows || (err == nil && review.PlaceID != placeID) {
+		apierror.Write(w, r, apierror.NotFound(apierror.CodeReviewNotFound, "Review not found"))
+		return false
+	}
+	if err != nil {
+		apierror.Write(w, r, err)
+		return false
+	}
+
+	if review.UserID != userID {
+		h.logger.WarnContext(r.Context(), "User attempted to modify another user's review", "user_id", userID, "review_id", reviewID, "author_id", review.UserID)
+		apierror.Write(w, r, apierror.Forbidden("You can only modify your own reviews"))
+		return false
+	}
+
+	return true
+}
+
+// decodeReviewRequest decodes and validates a review request body
+func (h *ReviewsHandler) decodeReviewRequest(w http.ResponseWriter, r *http.Request) (models.ReviewRequest, bool) {
+	var req models.ReviewRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.InfoContext(r.Context(), "Invalid request body", "err", err)
+		apierror.Write(w, r, apierror.BadRequest("Invalid request body"))
+		return req, false
+	}
+
+	if err := validateReviewRequest(&req); err != nil {
+		h.logger.InfoContext(r.Context(), "Invalid review", "err", err)
+		apierror.Write(w, r, err)
+		return req, false
+	}
+
+	return req, true
+}
+
+// validateReviewRequest checks score ranges and body length, reporting
+// every invalid field in metric order
+func validateReviewRequest(req *models.ReviewRequest) error {
+	v := validation.New()
+
+	rated := 0
+	for _, metric := range req.Scores.Metrics() {
+		if metric.Score == nil {
+			continue
+		}
+		v.Check(*metric.Score >= models.MinReviewScore && *metric.Score <= models.MaxReviewScore, "scores."+metric.Name,
+			fmt.Sprintf("must be between %d and %d", models.MinReviewScore, models.MaxReviewScore))
+		rated++
+	}
+	v.Check(rated > 0, "scores", "at least one metric must be scored")
+
+	req.Body = strings.TrimSpace(req.Body)
+	v.Check(len([]rune(req.Body)) <= maxReviewBodyLength, "body", fmt.Sprintf("must be at most %d characters", maxReviewBodyLength))
+
+	return v.Err()
+}
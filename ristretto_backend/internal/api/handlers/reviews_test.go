@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/validation"
+)
+
+func TestValidateReviewRequestReportsFieldsInOrder(t *testing.T) {
+	score := func(s int) *int { return &s }
+
+	tests := []struct {
+		name       string
+		req        models.ReviewRequest
+		wantFields []string
+	}{
+		{
+			name: "valid",
+			req:  models.ReviewRequest{Scores: models.ReviewScores{Espresso: score(4)}, Body: "Great shots"},
+		},
+		{
+			name:       "nothing scored",
+			req:        models.ReviewRequest{},
+			wantFields: []string{"scores"},
+		},
+		{
+			name: "several scores out of range",
+			req: models.ReviewRequest{
+				Scores: models.ReviewScores{Espresso: score(0), Wifi: score(3), Value: score(6), Service: score(9)},
+				Body:   strings.Repeat("a", maxReviewBodyLength+1),
+			},
+			wantFields: []string{"scores.espresso", "scores.value", "scores.service", "body"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Repeat to catch ordering that depends on map iteration
+			for i := 0; i < 20; i++ {
+				req := tt.req
+				err := validateReviewRequest(&req)
+
+				var got []string
+				var validationErr *validation.Error
+				if errors.As(err, &validationErr) {
+					for _, f := range validationErr.Fields {
+						got = append(got, f.Field)
+					}
+				} else if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if strings.Join(got, ",") != strings.Join(tt.wantFields, ",") {
+					t.Fatalf("fields = %v, want %v", got, tt.wantFields)
+				}
+			}
+		})
+	}
+}
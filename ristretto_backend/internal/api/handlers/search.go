@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/api/apierror"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/db"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/services"
+)
+
+const (
+	// minSearchQueryLength is the shortest query sent to the places provider
+	minSearchQueryLength = 2
+	// searchBiasRadius is the radius, in meters, of the area results are biased towards
+	searchBiasRadius = 10000.0
+	// defaultSearchResults and maxSearchResults bound the number of text search results
+	defaultSearchResults = 10
+	maxSearchResults     = 20
+	// maxSuggestions is the number of autocomplete suggestions returned
+	maxSuggestions = 8
+	// autocompleteTimeout caps how long autocomplete waits on the places provider
+	// before answering with local catalog matches only
+	autocompleteTimeout = 800 * time.Millisecond
+)
+
+// SearchHandler handles text search and autocomplete requests for coffee shops
+type SearchHandler struct {
+	db            *db.DB
+	placesService services.PlacesProvider
+	logger        *slog.Logger
+}
+
+// NewSearchHandler creates a new SearchHandler
+func NewSearchHandler(db *db.DB, placesService services.PlacesProvider, logger *slog.Logger) *SearchHandler {
+	return &SearchHandler{
+		db:            db,
+		placesService: placesService,
+		logger:        logger,
+	}
+}
+
+// HandleSearch handles GET /coffee_shops/search?q=, merging Places text
+// search results with matches from the local catalog
+func (h *SearchHandler) HandleSearch(w http.ResponseWriter, r *http.Request) {
+	// Get the authenticated user from the request context
+	identity, ok := requireIdentity(w, r)
+	if !ok {
+		return
+	}
+	userID := identity.UserID
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if len([]rune(query)) < minSearchQueryLength {
+		apierror.Write(w, r, apierror.BadRequest("Query must be at least 2 characters"))
+		return
+	}
+
+	maxResults := defaultSearchResults
+	if max, err := strconv.Atoi(r.URL.Query().Get("max")); err == nil && max > 0 {
+		maxResults = min(max, maxSearchResults)
+	}
+
+	bias := searchBias(r)
+	h.logger.DebugContext(r.Context(), "Searching coffee shops", "query", query, "user_id", userID)
+
+	// Query the places provider and the local catalog concurrently
+	var (
+		wg        sync.WaitGroup
+		places    []models.Place
+		placesErr error
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		places, placesErr = h.placesService.SearchText(placesContext(r), query, bias, maxResults)
+	}()
+
+	local, err := h.db.SearchCoffeeShops(query, false, maxResults)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to search local catalog", "err", err)
+	}
+	wg.Wait()
+
+	if placesErr != nil {
+		h.logger.WarnContext(r.Context(), "Failed to search places provider", "err", placesErr)
+		if err != nil {
+			apierror.Write(w, r, placesErr)
+			return
+		}
+	}
+
+	// Places results come first since they are ranked by relevance and
+	// proximity; local-only matches fill in the rest
+	coffeeShops := make([]models.CoffeeShop, 0, maxResults)
+	seen := make(map[string]bool)
+	for _, place := range places {
+		if seen[place.PlaceID] {
+			continue
+		}
+		seen[place.PlaceID] = true
+		coffeeShops = append(coffeeShops, models.CoffeeShop{
+			ID:        place.PlaceID,
+			Name:      place.DisplayName.Text,
+			Latitude:  place.Location.Latitude,
+			Longitude: place.Location.Longitude,
+		})
+	}
+	for _, shop := range local {
+		if seen[shop.PlaceID] {
+			continue
+		}
+		seen[shop.PlaceID] = true
+		coffeeShops = append(coffeeShops, models.CoffeeShop{
+			ID:        shop.PlaceID,
+			Name:      shop.Name,
+			Latitude:  shop.Latitude,
+			Longitude: shop.Longitude,
+		})
+	}
+	if len(coffeeShops) > maxResults {
+		coffeeShops = coffeeShops[:maxResults]
+	}
+
+	favoriteIDs, err := h.db.GetUserFavorites(userID)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to fetch user favorites", "err", err)
+		// Continue without favorites rather than failing
+		favoriteIDs = make(map[string]bool)
+	}
+	for i := range coffeeShops {
+		coffeeShops[i].IsFavorite = favoriteIDs[coffeeShops[i].ID]
+	}
+
+	h.logger.DebugContext(r.Context(), "Search completed", "query", query, "count", len(coffeeShops))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.CoffeeShopsResponse{
+		CoffeeShops: coffeeShops,
+	})
+}
+
+// HandleAutocomplete handles GET /coffee_shops/autocomplete?q=. Local catalog
+// prefix matches are always returned; places provider suggestions are merged
+// in only if they arrive within autocompleteTimeout.
+func (h *SearchHandler) HandleAutocomplete(w http.ResponseWriter, r *http.Request) {
+	input := strings.TrimSpace(r.URL.Query().Get("q"))
+	if input == "" {
+		apierror.Write(w, r, apierror.BadRequest("Query is required"))
+		return
+	}
+
+	var (
+		wg        sync.WaitGroup
+		remote    []models.PlaceSuggestion
+		remoteErr error
+	)
+	if len([]rune(input)) >= minSearchQueryLength {
+		ctx, cancel := context.WithTimeout(r.Context(), autocompleteTimeout)
+		defer cancel()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			remote, remoteErr = h.placesService.Autocomplete(ctx, input, searchBias(r), r.URL.Query().Get("sessionToken"))
+		}()
+	}
+
+	local, err := h.db.SearchCoffeeShops(input, true, maxSuggestions)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to search local catalog", "err", err)
+	}
+	wg.Wait()
+
+	if remoteErr != nil {
+		h.logger.WarnContext(r.Context(), "Autocomplete from places provider failed", "err", remoteErr)
+	}
+
+	// Our own catalog comes first since those shops have Ristretto reviews
+	suggestions := make([]models.PlaceSuggestion, 0, maxSuggestions)
+	seen := make(map[string]bool)
+	for _, shop := range local {
+		seen[shop.PlaceID] = true
+		suggestions = append(suggestions, models.PlaceSuggestion{
+			PlaceID:       shop.PlaceID,
+			Name:          shop.Name,
+			SecondaryText: shop.Address,
+		})
+	}
+	for _, suggestion := range remote {
+		if seen[suggestion.PlaceID] {
+			continue
+		}
+		seen[suggestion.PlaceID] = true
+		suggestions = append(suggestions, suggestion)
+	}
+	if len(suggestions) > maxSuggestions {
+		suggestions = suggestions[:maxSuggestions]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.CoffeeShopSuggestionsResponse{
+		Suggestions: suggestions,
+	})
+}
+
+// searchBias returns the area to bias results towards from the optional lat/lng query params
+func searchBias(r *http.Request) *models.Circle {
+	lat, latErr := strconv.ParseFloat(r.URL.Query().Get("lat"), 64)
+	lng, lngErr := strconv.ParseFloat(r.URL.Query().Get("lng"), 64)
+	if latErr != nil || lngErr != nil {
+		return nil
+	}
+
+	return &models.Circle{
+		Center: models.Center{Latitude: lat, Longitude: lng},
+		Radius: searchBiasRadius,
+	}
+}
@@ -2,62 +2,47 @@ package handlers
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
 
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/api/apierror"
 	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/db"
 	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
-	"github.com/RobertGarabetian/ristretto/ristretto_backend/pkg/utils"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/services"
 )
 
 // VisitsHandler handles requests for visit records
 type VisitsHandler struct {
-	db *db.DB
+	db            *db.DB
+	placesService services.PlacesProvider
+	logger        *slog.Logger
 }
 
 // NewVisitsHandler creates a new VisitsHandler
-func NewVisitsHandler(db *db.DB) *VisitsHandler {
+func NewVisitsHandler(db *db.DB, placesService services.PlacesProvider, logger *slog.Logger) *VisitsHandler {
 	return &VisitsHandler{
-		db: db,
+		db:            db,
+		placesService: placesService,
+		logger:        logger,
 	}
 }
 
-// HandleVisits handles requests to the /visits endpoint
-func (h *VisitsHandler) HandleVisits(w http.ResponseWriter, r *http.Request) {
-	// Get user ID from request header
-	userIDStr := r.Header.Get("X-User-ID")
-	userID, err := utils.ParseInt(userIDStr)
-	if err != nil {
-		log.Printf("Invalid user ID: %s", userIDStr)
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+// GetVisits handles GET /visits, returning the user's visit history
+func (h *VisitsHandler) GetVisits(w http.ResponseWriter, r *http.Request) {
+	// Get the authenticated user from the request context
+	identity, ok := requireIdentity(w, r)
+	if !ok {
 		return
 	}
-
-	log.Printf("Handling visits request: %s for user ID: %d", r.Method, userID)
-
-	switch r.Method {
-	case http.MethodGet:
-		h.getVisits(w, r, userID)
-	case http.MethodPost:
-		h.addVisit(w, r, userID)
-	default:
-		log.Printf("Method not allowed: %s", r.Method)
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
-}
-
-// getVisits gets a user's visit history
-func (h *VisitsHandler) getVisits(w http.ResponseWriter, r *http.Request, userID int) {
-	log.Printf("Getting visit history for user ID: %d", userID)
+	userID := identity.UserID
 
 	visits, err := h.db.GetVisits(userID)
 	if err != nil {
-		log.Printf("Database error fetching visits: %v", err)
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
-	log.Printf("Found %d visits for user ID: %d", len(visits), userID)
+	h.logger.DebugContext(r.Context(), "Fetched visits", "user_id", userID, "count", len(visits))
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -65,33 +50,43 @@ func (h *VisitsHandler) getVisits(w http.ResponseWriter, r *http.Request, userID
 	})
 }
 
-// addVisit records a coffee shop visit
-func (h *VisitsHandler) addVisit(w http.ResponseWriter, r *http.Request, userID int) {
+// AddVisit handles POST /visits, recording a coffee shop visit
+func (h *VisitsHandler) AddVisit(w http.ResponseWriter, r *http.Request) {
+	// Get the authenticated user from the request context
+	identity, ok := requireIdentity(w, r)
+	if !ok {
+		return
+	}
+	userID := identity.UserID
+
 	var coffeeShop models.CoffeeShop
 
 	if err := json.NewDecoder(r.Body).Decode(&coffeeShop); err != nil {
-		log.Printf("Invalid request body: %v", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		h.logger.InfoContext(r.Context(), "Invalid request body", "err", err)
+		apierror.Write(w, r, apierror.BadRequest("Invalid request body"))
 		return
 	}
 
-	if coffeeShop.ID == "" || coffeeShop.Name == "" {
-		log.Printf("Missing required fields: ID or Name")
-		http.Error(w, "Place ID and Name are required", http.StatusBadRequest)
+	if err := validateCoffeeShopBody(coffeeShop, false); err != nil {
+		h.logger.InfoContext(r.Context(), "Invalid coffee shop", "err", err)
+		apierror.Write(w, r, err)
 		return
 	}
 
-	log.Printf("Recording visit to %s (%s) for user ID: %d",
-		coffeeShop.Name, coffeeShop.ID, userID)
+	// Only attach user data to places that exist
+	if err := services.ValidatePlace(r.Context(), h.db, h.placesService, coffeeShop.ID, h.logger); err != nil {
+		h.logger.InfoContext(r.Context(), "Rejected unknown place", "place_id", coffeeShop.ID, "err", err)
+		apierror.Write(w, r, err)
+		return
+	}
 
 	err := h.db.AddVisit(userID, coffeeShop.ID, coffeeShop.Name)
 	if err != nil {
-		log.Printf("Database error recording visit: %v", err)
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
-	log.Printf("Successfully recorded visit")
+	h.logger.InfoContext(r.Context(), "Recorded visit", "user_id", userID, "place_id", coffeeShop.ID)
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/api/apierror"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/auth"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/db"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
+)
+
+// maxWebhookBodyBytes bounds the size of a webhook payload
+const maxWebhookBodyBytes = 1 << 20
+
+// ClerkWebhookHandler keeps the users table in sync with Clerk
+type ClerkWebhookHandler struct {
+	db       *db.DB
+	verifier *auth.WebhookVerifier
+	logger   *slog.Logger
+}
+
+// NewClerkWebhookHandler creates a new ClerkWebhookHandler
+func NewClerkWebhookHandler(db *db.DB, verifier *auth.WebhookVerifier, logger *slog.Logger) *ClerkWebhookHandler {
+	return &ClerkWebhookHandler{
+		db:       db,
+		verifier: verifier,
+		logger:   logger,
+	}
+}
+
+// HandleClerkWebhook handles POST /webhooks/clerk. Requests are authenticated
+// by their Svix signature rather than a user token.
+func (h *ClerkWebhookHandler) HandleClerkWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodyBytes))
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to read webhook body", "err", err)
+		apierror.Write(w, r, apierror.BadRequest("Invalid request body"))
+		return
+	}
+
+	if err := h.verifier.Verify(r.Header, body); err != nil {
+		h.logger.WarnContext(r.Context(), "Webhook verification failed", "err", err)
+		apierror.Write(w, r, apierror.Unauthenticated("Invalid signature"))
+		return
+	}
+
+	var event models.ClerkWebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to decode webhook event", "err", err)
+		apierror.Write(w, r, apierror.BadRequest("Invalid request body"))
+		return
+	}
+
+	var user models.ClerkUser
+	if err := json.Unmarshal(event.Data, &user); err != nil || user.ID == "" {
+		h.logger.WarnContext(r.Context(), "Webhook has no user data", "event", event.Type)
+		apierror.Write(w, r, apierror.BadRequest("Invalid event data"))
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "Handling Clerk webhook", "event", event.Type, "clerk_id", user.ID)
+
+	switch event.Type {
+	case models.ClerkEventUserCreated:
+		userID, err := h.db.UpsertUser(user.ID, user.PrimaryEmail(), stringValue(user.FirstName), stringValue(user.LastName))
+		if err != nil {
+			// A 5xx makes Svix retry the delivery
+			apierror.Write(w, r, err)
+			return
+		}
+		h.logger.InfoContext(r.Context(), "Created user from Clerk", "user_id", userID)
+
+	case models.ClerkEventUserUpdated:
+		// Only user.created inserts, so an update delivered after the user
+		// was deleted doesn't bring them back
+		updated, err := h.db.UpdateUserByClerkID(user.ID, user.PrimaryEmail(), stringValue(user.FirstName), stringValue(user.LastName))
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		h.logger.InfoContext(r.Context(), "Updated user from Clerk", "clerk_id", user.ID, "updated", updated)
+
+	case models.ClerkEventUserDeleted:
+		deleted, err := h.db.DeleteUserByClerkID(user.ID)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		h.logger.InfoContext(r.Context(), "Deleted user from Clerk", "clerk_id", user.ID, "deleted", deleted)
+
+	default:
+		// Acknowledge events we don't handle so they aren't retried
+		h.logger.DebugContext(r.Context(), "Ignoring Clerk webhook", "event", event.Type)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// stringValue dereferences an optional string, treating nil as empty
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/config"
+)
+
+// corsPolicy is a CORS configuration prepared for matching requests
+type corsPolicy struct {
+	origins          []originPattern
+	allowMethods     string
+	allowHeaders     string
+	exposeHeaders    string
+	allowCredentials bool
+	maxAge           string
+}
+
+// originPattern matches an origin such as https://app.example.com, or
+// https://*.example.com where * stands for one or more subdomain labels.
+// A lone * matches any origin.
+type originPattern struct {
+	prefix, suffix string
+	wildcard       bool
+}
+
+// newOriginPattern parses an allowed origin
+func newOriginPattern(origin string) originPattern {
+	origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+	prefix, suffix, wildcard := strings.Cut(origin, "*")
+	return originPattern{prefix: prefix, suffix: suffix, wildcard: wildcard}
+}
+
+// matches reports whether origin is allowed by the pattern
+func (p originPattern) matches(origin string) bool {
+	if !p.wildcard {
+		return origin == p.prefix
+	}
+	if len(origin) <= len(p.prefix)+len(p.suffix) ||
+		!strings.HasPrefix(origin, p.prefix) || !strings.HasSuffix(origin, p.suffix) {
+		return false
+	}
+	// The wildcard may not span into the path or port of the origin
+	middle := origin[len(p.prefix) : len(origin)-len(p.suffix)]
+	return p.prefix == "" && p.suffix == "" || !strings.ContainsAny(middle, "/:")
+}
+
+// allowed reports whether a request origin is allowed
+func (p *corsPolicy) allowed(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, pattern := range p.origins {
+		if pattern.matches(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// CORS applies the configured CORS policy to every response and answers
+// preflight requests before routing. Browsers send preflights without
+// credentials, so they must not reach the auth middleware. The matching
+// origin is echoed back rather than *, which browsers reject on requests
+// with credentials.
+func CORS(cfg config.CORSConfig, next http.Handler) http.Handler {
+	policy := &corsPolicy{
+		allowMethods:     strings.Join(cfg.AllowedMethods, ", "),
+		allowHeaders:     strings.Join(cfg.AllowedHeaders, ", "),
+		exposeHeaders:    strings.Join(cfg.ExposedHeaders, ", "),
+		allowCredentials: cfg.AllowCredentials,
+		maxAge:           strconv.Itoa(int(cfg.MaxAge.Seconds())),
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		policy.origins = append(policy.origins, newOriginPattern(origin))
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		// Responses differ by origin, so caches must key on it
+		w.Header().Add("Vary", "Origin")
+		if preflight {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if origin != "" && policy.allowed(origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			if policy.allowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+			if preflight {
+				w.Header().Set("Access-Control-Allow-Methods", policy.allowMethods)
+				w.Header().Set("Access-Control-Allow-Headers", policy.allowHeaders)
+				w.Header().Set("Access-Control-Max-Age", policy.maxAge)
+			} else if policy.exposeHeaders != "" {
+				w.Header().Set("Access-Control-Expose-Headers", policy.exposeHeaders)
+			}
+		}
+
+		// Disallowed origins get a preflight response without CORS headers,
+		// which the browser treats as a refusal
+		if preflight {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// identityHeaders are headers that earlier versions of the API used to pass
+// the authenticated user to handlers. Identity now travels in the request
+// context, but the headers are still stripped so nothing downstream can be
+// fooled by client-supplied values.
+var identityHeaders = []string{"X-User-ID", "X-Clerk-ID"}
+
+// StripIdentityHeaders removes client-supplied identity headers from every request
+func StripIdentityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, header := range identityHeaders {
+			r.Header.Del(header)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/api/apierror"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/metrics"
+)
+
+// routeKey is the context key for the route label filled in by Route
+type routeKey struct{}
+
+// Metrics records the latency and status of every request. Requests are
+// labeled with the route pattern set by Route, or as unmatched.
+func Metrics(m *metrics.HTTPMetrics, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		route := metrics.UnmatchedRoute
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), routeKey{}, &route)))
+
+		m.Observe(r.Method, route, rec.status, time.Since(start))
+	})
+}
+
+// Route labels requests served by next with their route pattern, such as
+// /coffee_shops/{placeId}, for the Metrics middleware
+func Route(pattern string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if route, ok := r.Context().Value(routeKey{}).(*string); ok {
+			*route = pattern
+		}
+		next(w, r)
+	}
+}
+
+// MetricsToken protects the metrics endpoint with a static bearer token.
+// An empty token leaves the endpoint open.
+func MetricsToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" {
+			provided := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				apierror.Write(w, r, apierror.Unauthenticated("Invalid metrics token"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
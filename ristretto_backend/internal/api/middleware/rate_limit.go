@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/api/apierror"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/auth"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/db"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/ratelimit"
+)
+
+// RateLimitUser returns middleware that limits each authenticated user. It
+// reads the identity set by the auth middleware, so it must run after it.
+// A nil limiter disables limiting.
+func RateLimitUser(limiter *ratelimit.Limiter, trustProxy bool) func(*db.DB, http.HandlerFunc) http.HandlerFunc {
+	return func(db *db.DB, next http.HandlerFunc) http.HandlerFunc {
+		if limiter == nil {
+			return next
+		}
+		return func(w http.ResponseWriter, r *http.Request) {
+			key := "ip:" + clientIP(r, trustProxy)
+			if identity, ok := auth.IdentityFromContext(r.Context()); ok {
+				key = "user:" + strconv.Itoa(identity.UserID)
+			}
+			if allow(w, r, limiter, key) {
+				next(w, r)
+			}
+		}
+	}
+}
+
+// RateLimitIP returns middleware that limits each client IP, for routes
+// that are not authenticated with a user token. A nil limiter disables
+// limiting.
+func RateLimitIP(limiter *ratelimit.Limiter, trustProxy bool) func(*db.DB, http.HandlerFunc) http.HandlerFunc {
+	return func(db *db.DB, next http.HandlerFunc) http.HandlerFunc {
+		if limiter == nil {
+			return next
+		}
+		return func(w http.ResponseWriter, r *http.Request) {
+			if allow(w, r, limiter, "ip:"+clientIP(r, trustProxy)) {
+				next(w, r)
+			}
+		}
+	}
+}
+
+// allow takes a token for key, setting the RateLimit-* headers, and answers
+// 429 if the bucket is empty
+func allow(w http.ResponseWriter, r *http.Request, limiter *ratelimit.Limiter, key string) bool {
+	result := limiter.Allow(key)
+
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", seconds(result.Reset))
+	if result.Allowed {
+		return true
+	}
+
+	w.Header().Set("Retry-After", seconds(result.RetryAfter))
+	apierror.Write(w, r, apierror.TooManyRequests("Too many requests, please slow down"))
+	return false
+}
+
+// seconds formats a duration as whole seconds, rounded up
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// clientIP returns the IP a request came from. Behind a trusted reverse
+// proxy it is the last X-Forwarded-For entry, which the proxy appended;
+// earlier entries are supplied by the client and can be forged.
+func clientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+			hops := strings.Split(forwarded[len(forwarded)-1], ",")
+			if ip := strings.TrimSpace(hops[len(hops)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
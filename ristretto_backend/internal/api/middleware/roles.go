@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/api/apierror"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/auth"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/db"
+)
+
+// RequireRole returns middleware that only lets through users holding role.
+// It reads the identity set by the auth middleware, so it must run after it.
+func RequireRole(role string, logger *slog.Logger) func(*db.DB, http.HandlerFunc) http.HandlerFunc {
+	return func(db *db.DB, next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			identity, ok := auth.IdentityFromContext(r.Context())
+			if !ok {
+				logger.ErrorContext(r.Context(), "No authenticated user for role check", "role", role)
+				apierror.Write(w, r, apierror.Unauthenticated("Unauthorized"))
+				return
+			}
+
+			if !identity.HasRole(role) {
+				logger.WarnContext(r.Context(), "User lacks required role", "user_id", identity.UserID, "role", role)
+				apierror.Write(w, r, apierror.Forbidden("Forbidden"))
+				return
+			}
+
+			next(w, r)
+		}
+	}
+}
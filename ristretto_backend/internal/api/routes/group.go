@@ -0,0 +1,55 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/api/middleware"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/db"
+)
+
+// Chain combines middlewares into one. The first middleware runs first.
+func Chain(middlewares ...Middleware) Middleware {
+	return func(db *db.DB, next http.HandlerFunc) http.HandlerFunc {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			next = middlewares[i](db, next)
+		}
+		return next
+	}
+}
+
+// Group registers routes that share a path prefix and a middleware chain.
+// Routes are registered as Go 1.22 ServeMux patterns, so the mux matches
+// methods and path parameters and answers other methods with 405 and an
+// Allow header.
+type Group struct {
+	mux         *http.ServeMux
+	db          *db.DB
+	prefix      string
+	middlewares []Middleware
+}
+
+// NewGroup creates a route group on mux
+func NewGroup(mux *http.ServeMux, db *db.DB, prefix string, middlewares ...Middleware) *Group {
+	return &Group{
+		mux:         mux,
+		db:          db,
+		prefix:      prefix,
+		middlewares: middlewares,
+	}
+}
+
+// Group creates a subgroup that extends the prefix and runs its middlewares
+// after those of the parent group
+func (g *Group) Group(prefix string, middlewares ...Middleware) *Group {
+	chain := append(append([]Middleware(nil), g.middlewares...), middlewares...)
+	return NewGroup(g.mux, g.db, g.prefix+prefix, chain...)
+}
+
+// Handle registers handler for method and a path relative to the group
+// prefix, e.g. g.Handle(http.MethodGet, "/{placeId}", h). The pattern
+// labels the route's metrics, so requests rejected by the group middlewares
+// are still counted against it.
+func (g *Group) Handle(method, path string, handler http.HandlerFunc) {
+	pattern := g.prefix + path
+	g.mux.HandleFunc(method+" "+pattern, middleware.Route(pattern, Chain(g.middlewares...)(g.db, handler)))
+}
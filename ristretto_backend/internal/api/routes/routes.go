@@ -1,12 +1,15 @@
 package routes
 
 import (
+	"log/slog"
 	"net/http"
-	"strings"
 
 	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/api/handlers"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/api/middleware"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/auth"
 	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/config"
 	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/db"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/ratelimit"
 	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/services"
 )
 
@@ -14,45 +17,85 @@ import (
 type Middleware func(*db.DB, http.HandlerFunc) http.HandlerFunc
 
 // Register registers all routes with the provided http.ServeMux
-func Register(mux *http.ServeMux, db *db.DB, authMiddleware Middleware) {
-	// Create services
-	placesService := services.NewPlacesService(getGoogleAPIKey())
+func Register(mux *http.ServeMux, db *db.DB, cfg *config.Config, placesService services.PlacesProvider, photoCache services.CacheBackend, authMiddleware Middleware, webhookVerifier *auth.WebhookVerifier, logger *slog.Logger) {
+	allowMockFallback := cfg.AllowMockFallback()
 
-	// Coffee Shops routes
-	coffeeShopsHandler := handlers.NewCoffeeShopsHandler(db, placesService)
-	coffeeShopDetailsHandler := handlers.NewCoffeeShopDetailsHandler(db, placesService)
+	// Rate limiters, left nil when rate limiting is disabled. Every route is
+	// limited per client IP. Authenticated routes are limited per IP ahead of
+	// auth, so floods of bad tokens never reach token verification, with a
+	// looser limit that leaves room for several users behind one address,
+	// and then per user.
+	var userLimiter, ipLimiter, authIPLimiter *ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		userLimiter = ratelimit.New(cfg.RateLimit.UserRequests, cfg.RateLimit.Interval, cfg.RateLimit.UserBurst)
+		ipLimiter = ratelimit.New(cfg.RateLimit.IPRequests, cfg.RateLimit.Interval, cfg.RateLimit.IPBurst)
+		authIPLimiter = ratelimit.New(cfg.RateLimit.AuthIPRequests, cfg.RateLimit.Interval, cfg.RateLimit.AuthIPBurst)
+	}
+	userRateLimit := middleware.RateLimitUser(userLimiter, cfg.RateLimit.TrustProxyHeaders)
+	ipRateLimit := middleware.RateLimitIP(ipLimiter, cfg.RateLimit.TrustProxyHeaders)
+	authIPRateLimit := middleware.RateLimitIP(authIPLimiter, cfg.RateLimit.TrustProxyHeaders)
 
-	// Handle /coffee_shops/{placeId} and /coffee_shops
-	mux.HandleFunc("/coffee_shops/", func(w http.ResponseWriter, r *http.Request) {
-		// Extract path after /coffee_shops/
-		path := strings.TrimPrefix(r.URL.Path, "/coffee_shops/")
+	// Coffee shop routes
+	coffeeShopsHandler := handlers.NewCoffeeShopsHandler(db, placesService, cfg.Search, allowMockFallback, logger)
+	coffeeShopDetailsHandler := handlers.NewCoffeeShopDetailsHandler(db, placesService, cfg.Search, allowMockFallback, logger)
+	reviewsHandler := handlers.NewReviewsHandler(db, placesService, logger)
+	searchHandler := handlers.NewSearchHandler(db, placesService, logger)
 
-		// If there's a placeId in the path, route to the details handler
-		if path != "" {
-			authMiddleware(db, coffeeShopDetailsHandler.HandleCoffeeShopDetails)(w, r)
-			return
-		}
+	coffeeShops := NewGroup(mux, db, "/coffee_shops", authIPRateLimit, authMiddleware, userRateLimit)
+	coffeeShops.Handle(http.MethodGet, "", coffeeShopsHandler.HandleCoffeeShops)
+	coffeeShops.Handle(http.MethodGet, "/{$}", coffeeShopsHandler.HandleCoffeeShops)
+	coffeeShops.Handle(http.MethodGet, "/search", searchHandler.HandleSearch)
+	coffeeShops.Handle(http.MethodGet, "/autocomplete", searchHandler.HandleAutocomplete)
+	coffeeShops.Handle(http.MethodGet, "/{placeId}", coffeeShopDetailsHandler.HandleCoffeeShopDetails)
 
-		// Otherwise, route to the list handler
-		authMiddleware(db, coffeeShopsHandler.HandleCoffeeShops)(w, r)
-	})
+	reviews := coffeeShops.Group("/{placeId}/reviews")
+	reviews.Handle(http.MethodGet, "", reviewsHandler.GetReviews)
+	reviews.Handle(http.MethodPost, "", reviewsHandler.CreateReview)
+	reviews.Handle(http.MethodPut, "/{reviewId}", reviewsHandler.UpdateReview)
+	reviews.Handle(http.MethodDelete, "/{reviewId}", reviewsHandler.DeleteReview)
 
 	// User routes
-	userHandler := handlers.NewUserHandler(db)
-	mux.HandleFunc("/user", authMiddleware(db, userHandler.HandleUser))
+	userHandler := handlers.NewUserHandler(db, logger)
+	favoritesHandler := handlers.NewFavoritesHandler(db, placesService, logger)
+	visitsHandler := handlers.NewVisitsHandler(db, placesService, logger)
 
-	// Favorites routes
-	favoritesHandler := handlers.NewFavoritesHandler(db)
-	mux.HandleFunc("/favorites", authMiddleware(db, favoritesHandler.HandleFavorites))
+	user := NewGroup(mux, db, "", authIPRateLimit, authMiddleware, userRateLimit)
+	user.Handle(http.MethodGet, "/user", userHandler.GetUserProfile)
+	user.Handle(http.MethodGet, "/favorites", favoritesHandler.GetFavorites)
+	user.Handle(http.MethodPost, "/favorites", favoritesHandler.AddFavorite)
+	user.Handle(http.MethodDelete, "/favorites", favoritesHandler.RemoveFavorite)
+	user.Handle(http.MethodGet, "/visits", visitsHandler.GetVisits)
+	user.Handle(http.MethodPost, "/visits", visitsHandler.AddVisit)
 
-	// Visits routes
-	visitsHandler := handlers.NewVisitsHandler(db)
-	mux.HandleFunc("/visits", authMiddleware(db, visitsHandler.HandleVisits))
-}
+	// Admin routes. Review moderation is also open to moderators.
+	adminHandler := handlers.NewAdminHandler(db, placesService, logger)
+
+	moderator := NewGroup(mux, db, "/admin", authIPRateLimit, authMiddleware, userRateLimit, middleware.RequireRole(auth.RoleModerator, logger))
+	moderator.Handle(http.MethodPut, "/reviews/{reviewId}/hidden", adminHandler.SetReviewHidden)
+
+	admin := NewGroup(mux, db, "/admin", authIPRateLimit, authMiddleware, userRateLimit, middleware.RequireRole(auth.RoleAdmin, logger))
+	admin.Handle(http.MethodGet, "/users", adminHandler.ListUsers)
+	admin.Handle(http.MethodGet, "/users/{userId}", adminHandler.GetUser)
+	admin.Handle(http.MethodPut, "/users/{userId}/role", adminHandler.SetUserRole)
+	admin.Handle(http.MethodGet, "/users/{userId}/favorites", adminHandler.GetUserFavorites)
+	admin.Handle(http.MethodGet, "/users/{userId}/visits", adminHandler.GetUserVisits)
+	admin.Handle(http.MethodPost, "/coffee_shops/{placeId}/refresh", adminHandler.RefreshCoffeeShop)
 
-// getGoogleAPIKey retrieves the Google API key from environment variables
-func getGoogleAPIKey() string {
-	cfg := config.Load()
+	// Photo proxy routes. They are public so image tags can load them, and
+	// only serve signed Google Places photo URLs, since fetches count against
+	// the call budget. A nil photoCache disables photo caching.
+	if fetcher, ok := placesService.(services.PhotoFetcher); ok {
+		photosHandler := handlers.NewPhotosHandler(services.NewPhotoCache(fetcher, photoCache, cfg.Photos.CacheTTL, logger),
+			services.NewPhotoSigner([]byte(cfg.Photos.SigningKey)), cfg.Photos.MaxAge, logger)
+		photos := NewGroup(mux, db, "/photos", ipRateLimit)
+		photos.Handle(http.MethodGet, "/{name...}", photosHandler.HandlePhoto)
+	}
 
-	return cfg.Google.PlacesAPIKey // Placeholder, should be loaded from environment variables
+	// Webhook routes, authenticated by signature instead of a user token and
+	// only served when a signing secret is configured
+	if webhookVerifier != nil {
+		clerkWebhookHandler := handlers.NewClerkWebhookHandler(db, webhookVerifier, logger)
+		webhooks := NewGroup(mux, db, "/webhooks", ipRateLimit)
+		webhooks.Handle(http.MethodPost, "/clerk", clerkWebhookHandler.HandleClerkWebhook)
+	}
 }
@@ -0,0 +1,67 @@
+package auth
+
+import "context"
+
+// Roles a user can hold, stored in users.role. Each role includes the
+// permissions of the roles before it.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// roleHierarchy lists roles from least to most privileged
+var roleHierarchy = []string{RoleUser, RoleModerator, RoleAdmin}
+
+// ValidRole reports whether role is a known role
+func ValidRole(role string) bool {
+	for _, r := range roleHierarchy {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// RolesFor expands a user's stored role into every role it grants, so that
+// an admin also passes moderator and user checks. Unknown roles grant only
+// RoleUser.
+func RolesFor(role string) []string {
+	for i, r := range roleHierarchy {
+		if r == role {
+			return append([]string(nil), roleHierarchy[:i+1]...)
+		}
+	}
+	return []string{RoleUser}
+}
+
+// Identity is the authenticated user making a request
+type Identity struct {
+	UserID  int      // Internal user ID
+	ClerkID string   // Clerk user ID from the token subject
+	Roles   []string // Roles granted to the user
+}
+
+// HasRole reports whether the identity has been granted a role
+func (i *Identity) HasRole(role string) bool {
+	for _, r := range i.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// identityKey is the context key for the request identity
+type identityKey struct{}
+
+// WithIdentity returns a copy of ctx carrying the identity
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the identity stored in ctx, if any
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(*Identity)
+	return identity, ok && identity != nil
+}
@@ -0,0 +1,238 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minJWKSRefreshInterval limits how often the JWKS is refetched, so tokens
+// with made-up key IDs or an unreachable endpoint can't cause a fetch per request
+const minJWKSRefreshInterval = 30 * time.Second
+
+// KeySource resolves the public key a token was signed with
+type KeySource interface {
+	Key(ctx context.Context, kid string) (*rsa.PublicKey, error)
+}
+
+// jwk is a single JSON Web Key. Only RSA signing keys are supported.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// jwkSet is a JSON Web Key Set as published at Clerk's /.well-known/jwks.json
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+// parseJWKS decodes a JWKS document into RSA public keys by key ID
+func parseJWKS(data []byte) (map[string]*rsa.PublicKey, error) {
+	var set jwkSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, key := range set.Keys {
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus for key %q: %w", key.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent for key %q: %w", key.Kid, err)
+		}
+
+		keys[key.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS contains no RSA signing keys")
+	}
+
+	return keys, nil
+}
+
+// JWKSCache resolves keys from a JWKS document, caching them for a TTL and
+// refetching early when a token names a key ID it hasn't seen, which is how
+// a key rotation shows up
+type JWKSCache struct {
+	load func(ctx context.Context) ([]byte, error)
+	ttl  time.Duration
+
+	mu          sync.Mutex
+	keys        map[string]*rsa.PublicKey
+	fetchedAt   time.Time
+	refreshedAt time.Time  // Last fetch attempt, successful or not
+	fetch       *jwksFetch // Fetch in flight, shared by every request waiting on it
+	now         func() time.Time
+}
+
+// jwksFetch is a JWKS fetch that concurrent requests wait on together
+type jwksFetch struct {
+	done chan struct{}
+	err  error
+}
+
+// NewJWKSURLCache creates a JWKSCache that fetches keys from a JWKS URL
+func NewJWKSURLCache(url string, client *http.Client, ttl time.Duration) *JWKSCache {
+	return &JWKSCache{
+		ttl: ttl,
+		now: time.Now,
+		load: func(ctx context.Context) ([]byte, error) {
+			req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+			if err != nil {
+				return nil, err
+			}
+
+			resp, err := client.Do(req)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				return nil, fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+			}
+			return io.ReadAll(resp.Body)
+		},
+	}
+}
+
+// NewJWKSFileCache creates a JWKSCache that reads keys from a local JWKS
+// file, re-reading it when the TTL expires or an unknown key ID appears
+func NewJWKSFileCache(path string, ttl time.Duration) *JWKSCache {
+	return &JWKSCache{
+		ttl: ttl,
+		now: time.Now,
+		load: func(ctx context.Context) ([]byte, error) {
+			return os.ReadFile(path)
+		},
+	}
+}
+
+// Key returns the public key with the given key ID
+func (c *JWKSCache) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	c.mu.Lock()
+	key, known := c.keys[kid]
+	if known && c.now().Sub(c.fetchedAt) <= c.ttl {
+		c.mu.Unlock()
+		return key, nil
+	}
+
+	// Refetch on expiry or an unknown key ID, unless we just tried. Known
+	// keys keep verifying in the meantime.
+	fetch := c.fetch
+	if fetch == nil && c.now().Sub(c.refreshedAt) > minJWKSRefreshInterval {
+		fetch = c.startRefresh()
+	}
+	c.mu.Unlock()
+
+	if fetch == nil {
+		if known {
+			return key, nil
+		}
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	select {
+	case <-fetch.done:
+	case <-ctx.Done():
+		if known {
+			return key, nil
+		}
+		return nil, ctx.Err()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if key, ok := c.keys[kid]; ok {
+		return key, nil
+	}
+	if fetch.err != nil {
+		return nil, fetch.err
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// startRefresh fetches the JWKS in the background and replaces the cached
+// keys on success. The fetch doesn't use any request's context, so a
+// request giving up doesn't fail it for the others. Callers must hold c.mu.
+func (c *JWKSCache) startRefresh() *jwksFetch {
+	fetch := &jwksFetch{done: make(chan struct{})}
+	c.fetch = fetch
+	c.refreshedAt = c.now()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+		defer cancel()
+
+		keys, err := c.loadKeys(ctx)
+
+		c.mu.Lock()
+		if err != nil {
+			slog.Error("Failed to refresh JWKS", "err", err)
+		} else {
+			slog.Info("Loaded signing keys from JWKS", "count", len(keys))
+			c.keys = keys
+			c.fetchedAt = c.now()
+		}
+		fetch.err = err
+		c.fetch = nil
+		c.mu.Unlock()
+		close(fetch.done)
+	}()
+
+	return fetch
+}
+
+// loadKeys fetches and parses the JWKS
+func (c *JWKSCache) loadKeys(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	data, err := c.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return parseJWKS(data)
+}
+
+// StaticKey is a KeySource with a single key used for every token, as
+// configured through a PEM encoded public key
+type StaticKey struct {
+	key *rsa.PublicKey
+}
+
+// NewStaticKey parses a PEM encoded RSA public key
+func NewStaticKey(pem string) (*StaticKey, error) {
+	key, err := jwt.ParseRSAPublicKeyFromPEM([]byte(pem))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse RSA public key: %w", err)
+	}
+	return &StaticKey{key: key}, nil
+}
+
+// Key returns the static key regardless of key ID
+func (s *StaticKey) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	return s.key, nil
+}
@@ -0,0 +1,310 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
+)
+
+// jwksServer is a stand-in for Clerk's JWKS endpoint whose keys can be rotated
+type jwksServer struct {
+	*httptest.Server
+	fetches atomic.Int32
+
+	mu     sync.Mutex
+	keys   map[string]*rsa.PrivateKey
+	failed bool
+}
+
+// newJWKSServer starts a JWKS server publishing keys
+func newJWKSServer(t *testing.T, keys map[string]*rsa.PrivateKey) *jwksServer {
+	t.Helper()
+	s := &jwksServer{keys: keys}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// serve writes the published keys as a JWKS document
+func (s *jwksServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.fetches.Add(1)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failed {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var set jwkSet
+	for kid, key := range s.keys {
+		set.Keys = append(set.Keys, jwk{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	json.NewEncoder(w).Encode(set)
+}
+
+// publish replaces the published keys
+func (s *jwksServer) publish(keys map[string]*rsa.PrivateKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+// fail makes the endpoint return 500s
+func (s *jwksServer) fail() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failed = true
+}
+
+// fakeClock is a manually advanced clock
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// Now returns the current fake time
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// newTestCache creates a JWKSCache for server driven by a fake clock
+func newTestCache(server *jwksServer, ttl time.Duration) (*JWKSCache, *fakeClock) {
+	clock := &fakeClock{now: time.Now()}
+	cache := NewJWKSURLCache(server.URL, server.Client(), ttl)
+	cache.now = clock.Now
+	return cache, clock
+}
+
+// generateKey creates an RSA signing key
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return key
+}
+
+// signToken signs claims with key under kid
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims *models.ClerkClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return signed
+}
+
+// validClaims returns claims that pass every check of testVerifierOptions
+func validClaims() *models.ClerkClaims {
+	now := time.Now()
+	return &models.ClerkClaims{
+		AuthorizedParty: "https://app.example.com",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "user_123",
+			Issuer:    "https://clerk.example.com",
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+	}
+}
+
+// testVerifierOptions are the checks used by the verifier tests
+var testVerifierOptions = VerifierOptions{
+	Issuer:            "https://clerk.example.com",
+	AuthorizedParties: []string{"https://app.example.com"},
+	ClockSkew:         5 * time.Second,
+}
+
+func TestJWKSCacheRefreshesOnUnknownKey(t *testing.T) {
+	oldKey, newKey := generateKey(t), generateKey(t)
+	server := newJWKSServer(t, map[string]*rsa.PrivateKey{"old": oldKey})
+	cache, clock := newTestCache(server, time.Hour)
+	verifier := NewVerifier(cache, testVerifierOptions)
+	ctx := context.Background()
+
+	if _, err := verifier.Verify(ctx, signToken(t, oldKey, "old", validClaims())); err != nil {
+		t.Fatalf("verify with initial key: %v", err)
+	}
+
+	// Clerk rotates to a new key, which tokens start naming
+	server.publish(map[string]*rsa.PrivateKey{"old": oldKey, "new": newKey})
+	clock.Advance(minJWKSRefreshInterval + time.Second)
+
+	if _, err := verifier.Verify(ctx, signToken(t, newKey, "new", validClaims())); err != nil {
+		t.Fatalf("verify with rotated key: %v", err)
+	}
+	if got := server.fetches.Load(); got != 2 {
+		t.Errorf("fetches = %d, want 2", got)
+	}
+
+	// Keys stay cached until the TTL expires
+	if _, err := verifier.Verify(ctx, signToken(t, oldKey, "old", validClaims())); err != nil {
+		t.Fatalf("verify with cached key: %v", err)
+	}
+	if got := server.fetches.Load(); got != 2 {
+		t.Errorf("fetches after cached lookup = %d, want 2", got)
+	}
+}
+
+func TestJWKSCacheRateLimitsRefresh(t *testing.T) {
+	key := generateKey(t)
+	server := newJWKSServer(t, map[string]*rsa.PrivateKey{"a": key})
+	cache, clock := newTestCache(server, time.Hour)
+	ctx := context.Background()
+
+	if _, err := cache.Key(ctx, "a"); err != nil {
+		t.Fatalf("initial key: %v", err)
+	}
+
+	// Made-up key IDs can't trigger another fetch within the interval
+	for i := 0; i < 5; i++ {
+		if _, err := cache.Key(ctx, "made-up"); err == nil {
+			t.Fatal("unknown key ID was accepted")
+		}
+	}
+	if got := server.fetches.Load(); got != 1 {
+		t.Fatalf("fetches = %d, want 1", got)
+	}
+
+	// Once the interval has passed, concurrent lookups share one fetch
+	clock.Advance(minJWKSRefreshInterval + time.Second)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.Key(ctx, "made-up")
+		}()
+	}
+	wg.Wait()
+	if got := server.fetches.Load(); got != 2 {
+		t.Errorf("fetches after concurrent lookups = %d, want 2", got)
+	}
+}
+
+func TestJWKSCacheServesKnownKeysWhileEndpointIsDown(t *testing.T) {
+	key := generateKey(t)
+	server := newJWKSServer(t, map[string]*rsa.PrivateKey{"a": key})
+	cache, clock := newTestCache(server, time.Minute)
+	ctx := context.Background()
+
+	if _, err := cache.Key(ctx, "a"); err != nil {
+		t.Fatalf("initial key: %v", err)
+	}
+
+	server.fail()
+	clock.Advance(2 * time.Minute)
+
+	// The failed refresh is throttled like any other, and the expired key
+	// keeps verifying
+	for i := 0; i < 5; i++ {
+		if _, err := cache.Key(ctx, "a"); err != nil {
+			t.Fatalf("lookup %d while endpoint is down: %v", i, err)
+		}
+	}
+	if got := server.fetches.Load(); got != 2 {
+		t.Errorf("fetches = %d, want 2", got)
+	}
+}
+
+func TestVerifierRejectsInvalidClaims(t *testing.T) {
+	key := generateKey(t)
+	server := newJWKSServer(t, map[string]*rsa.PrivateKey{"a": key})
+	cache, _ := newTestCache(server, time.Hour)
+	verifier := NewVerifier(cache, testVerifierOptions)
+
+	tests := []struct {
+		name    string
+		modify  func(claims *models.ClerkClaims)
+		wantErr bool
+	}{
+		{
+			name:   "valid",
+			modify: func(claims *models.ClerkClaims) {},
+		},
+		{
+			name:    "wrong issuer",
+			modify:  func(claims *models.ClerkClaims) { claims.Issuer = "https://evil.example.com" },
+			wantErr: true,
+		},
+		{
+			name:    "unauthorized party",
+			modify:  func(claims *models.ClerkClaims) { claims.AuthorizedParty = "https://evil.example.com" },
+			wantErr: true,
+		},
+		{
+			name: "not yet valid",
+			modify: func(claims *models.ClerkClaims) {
+				claims.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Minute))
+			},
+			wantErr: true,
+		},
+		{
+			name: "not yet valid within clock skew",
+			modify: func(claims *models.ClerkClaims) {
+				claims.NotBefore = jwt.NewNumericDate(time.Now().Add(2 * time.Second))
+			},
+		},
+		{
+			name: "expired",
+			modify: func(claims *models.ClerkClaims) {
+				claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+			},
+			wantErr: true,
+		},
+		{
+			name: "expired within clock skew",
+			modify: func(claims *models.ClerkClaims) {
+				claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-2 * time.Second))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims()
+			tt.modify(claims)
+
+			_, err := verifier.Verify(context.Background(), signToken(t, key, "a", claims))
+			if tt.wantErr && err == nil {
+				t.Error("token was accepted")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("token was rejected: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testWebhookKey is the signing key behind testWebhookSecret
+var testWebhookKey = []byte("test webhook signing key")
+
+// testWebhookSecret is testWebhookKey as Clerk shows it in the dashboard
+var testWebhookSecret = "whsec_" + base64.StdEncoding.EncodeToString(testWebhookKey)
+
+// signWebhook returns the v1 signature of a message with key
+func signWebhook(key []byte, msgID, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(msgID + "." + timestamp + "."))
+	mac.Write(body)
+	return "v1," + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// webhookHeader returns Svix headers for a message
+func webhookHeader(msgID, timestamp, signature string) http.Header {
+	header := make(http.Header)
+	if msgID != "" {
+		header.Set("svix-id", msgID)
+	}
+	if timestamp != "" {
+		header.Set("svix-timestamp", timestamp)
+	}
+	if signature != "" {
+		header.Set("svix-signature", signature)
+	}
+	return header
+}
+
+func TestWebhookVerifierVerify(t *testing.T) {
+	verifier, err := NewWebhookVerifier(testWebhookSecret)
+	if err != nil {
+		t.Fatalf("create verifier: %v", err)
+	}
+
+	const msgID = "msg_2abc"
+	body := []byte(`{"type":"user.created","data":{"id":"user_1"}}`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	valid := signWebhook(testWebhookKey, msgID, now, body)
+	at := func(offset time.Duration) string {
+		return strconv.FormatInt(time.Now().Add(offset).Unix(), 10)
+	}
+
+	tests := []struct {
+		name    string
+		header  http.Header
+		body    []byte
+		wantErr bool
+	}{
+		{name: "valid signature", header: webhookHeader(msgID, now, valid), body: body},
+		{
+			name:    "tampered body",
+			header:  webhookHeader(msgID, now, valid),
+			body:    []byte(`{"type":"user.created","data":{"id":"user_2"}}`),
+			wantErr: true,
+		},
+		{
+			name:    "signed for another message",
+			header:  webhookHeader("msg_other", now, valid),
+			body:    body,
+			wantErr: true,
+		},
+		{
+			name:    "expired timestamp",
+			header:  webhookHeader(msgID, at(-10*time.Minute), signWebhook(testWebhookKey, msgID, at(-10*time.Minute), body)),
+			body:    body,
+			wantErr: true,
+		},
+		{
+			name:    "future timestamp",
+			header:  webhookHeader(msgID, at(10*time.Minute), signWebhook(testWebhookKey, msgID, at(10*time.Minute), body)),
+			body:    body,
+			wantErr: true,
+		},
+		{
+			name:    "malformed timestamp",
+			header:  webhookHeader(msgID, "yesterday", valid),
+			body:    body,
+			wantErr: true,
+		},
+		{
+			name:   "one of several signatures matches",
+			header: webhookHeader(msgID, now, signWebhook([]byte("rotated key"), msgID, now, body)+" v1,bm90IGJhc2U2NA== "+valid),
+			body:   body,
+		},
+		{
+			name:    "no signature matches",
+			header:  webhookHeader(msgID, now, signWebhook([]byte("rotated key"), msgID, now, body)+" v1,bm90IGJhc2U2NA=="),
+			body:    body,
+			wantErr: true,
+		},
+		{
+			name:    "unknown signature version",
+			header:  webhookHeader(msgID, now, "v2"+valid[2:]),
+			body:    body,
+			wantErr: true,
+		},
+		{name: "missing id", header: webhookHeader("", now, valid), body: body, wantErr: true},
+		{name: "missing timestamp", header: webhookHeader(msgID, "", valid), body: body, wantErr: true},
+		{name: "missing signature", header: webhookHeader(msgID, now, ""), body: body, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifier.Verify(tt.header, tt.body)
+			if tt.wantErr && err == nil {
+				t.Error("webhook was accepted")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("webhook was rejected: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewWebhookVerifierRejectsInvalidSecrets(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString(testWebhookKey)
+
+	tests := []struct {
+		name    string
+		secret  string
+		wantErr bool
+	}{
+		{name: "dashboard secret", secret: testWebhookSecret},
+		{name: "wrong prefix", secret: "sk_test_" + encoded, wantErr: true},
+		{name: "no prefix", secret: encoded, wantErr: true},
+		{name: "misspelled prefix", secret: "whsec-" + encoded, wantErr: true},
+		{name: "not base64", secret: "whsec_not base64!", wantErr: true},
+		{name: "empty key", secret: "whsec_", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewWebhookVerifier(tt.secret)
+			if tt.wantErr && err == nil {
+				t.Error("secret was accepted")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("secret was rejected: %v", err)
+			}
+		})
+	}
+}
+
+func TestWebhookVerifierRejectsOtherSecrets(t *testing.T) {
+	verifier, err := NewWebhookVerifier("whsec_" + base64.StdEncoding.EncodeToString([]byte("another key")))
+	if err != nil {
+		t.Fatalf("create verifier: %v", err)
+	}
+
+	const msgID = "msg_2abc"
+	body := []byte(`{"type":"user.deleted"}`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+
+	if err := verifier.Verify(webhookHeader(msgID, now, signWebhook(testWebhookKey, msgID, now, body)), body); err == nil {
+		t.Error("webhook signed with another secret was accepted")
+	}
+}
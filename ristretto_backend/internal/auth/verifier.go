@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/config"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
+)
+
+// jwksFetchTimeout bounds a single JWKS fetch
+const jwksFetchTimeout = 10 * time.Second
+
+// VerifierOptions are the claims checks applied to every token
+type VerifierOptions struct {
+	Issuer            string        // Required iss claim; empty skips the check
+	AuthorizedParties []string      // Allowed azp claims; empty skips the check
+	ClockSkew         time.Duration // Leeway applied to exp, nbf and iat
+}
+
+// Verifier verifies Clerk session tokens
+type Verifier struct {
+	keys KeySource
+	opts VerifierOptions
+}
+
+// NewVerifier creates a Verifier that resolves signing keys from keys
+func NewVerifier(keys KeySource, opts VerifierOptions) *Verifier {
+	return &Verifier{
+		keys: keys,
+		opts: opts,
+	}
+}
+
+// NewVerifierFromConfig creates a Verifier using the configured JWKS URL,
+// JWKS file or PEM public key, in that order of preference
+func NewVerifierFromConfig(cfg config.AuthConfig) (*Verifier, error) {
+	var keys KeySource
+	switch {
+	case cfg.JWKSURL != "":
+		keys = NewJWKSURLCache(cfg.JWKSURL, &http.Client{Timeout: jwksFetchTimeout}, cfg.JWKSCacheTTL)
+	case cfg.JWKSFile != "":
+		keys = NewJWKSFileCache(cfg.JWKSFile, cfg.JWKSCacheTTL)
+	case cfg.ClerkJWTPublicKey != "":
+		staticKey, err := NewStaticKey(cfg.ClerkJWTPublicKey)
+		if err != nil {
+			return nil, err
+		}
+		keys = staticKey
+	default:
+		return nil, fmt.Errorf("one of CLERK_JWKS_URL, CLERK_JWKS_FILE or CLERK_JWT_PUBLIC_KEY is required")
+	}
+
+	return NewVerifier(keys, VerifierOptions{
+		Issuer:            cfg.Issuer,
+		AuthorizedParties: cfg.AuthorizedParties,
+		ClockSkew:         cfg.ClockSkew,
+	}), nil
+}
+
+// Verify checks a token's signature and claims and returns its claims
+func (v *Verifier) Verify(ctx context.Context, tokenString string) (*models.ClerkClaims, error) {
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithLeeway(v.opts.ClockSkew),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if v.opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(v.opts.Issuer))
+	}
+
+	claims := &models.ClerkClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return v.keys.Key(ctx, kid)
+	}, parserOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("token has no subject")
+	}
+	if err := v.checkAuthorizedParty(claims.AuthorizedParty); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// checkAuthorizedParty rejects tokens issued to an origin we don't serve.
+// Tokens without an azp claim are allowed, as Clerk omits it for non-browser clients.
+func (v *Verifier) checkAuthorizedParty(azp string) error {
+	if azp == "" || len(v.opts.AuthorizedParties) == 0 {
+		return nil
+	}
+	for _, party := range v.opts.AuthorizedParties {
+		if azp == party {
+			return nil
+		}
+	}
+	return fmt.Errorf("unauthorized party %q", azp)
+}
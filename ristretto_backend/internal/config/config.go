@@ -1,20 +1,47 @@
 package config
 
 import (
+	"log/slog"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment is the deployment environment the server runs in
+type Environment string
+
+// Supported environments, selected with APP_ENV
+const (
+	EnvDevelopment Environment = "development"
+	EnvStaging     Environment = "staging"
+	EnvProduction  Environment = "production"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Database   DatabaseConfig
-	Google     GoogleConfig
-	Auth       AuthConfig
-	ServerPort string
+	Environment Environment
+	Database    DatabaseConfig
+	Google      GoogleConfig
+	Places      PlacesConfig
+	Cache       CacheConfig
+	Photos      PhotoConfig
+	Catalog     CatalogConfig
+	Auth        AuthConfig
+	Search      SearchConfig
+	Logging     LoggingConfig
+	Metrics     MetricsConfig
+	Server      ServerConfig
+	CORS        CORSConfig
+	RateLimit   RateLimitConfig
+	ServerPort  string
 }
 
 // DatabaseConfig holds database connection information
 type DatabaseConfig struct {
 	ConnectionString string
+	AutoMigrate      bool // Apply pending migrations at server startup
 }
 
 // GoogleConfig holds Google API configuration
@@ -22,9 +49,120 @@ type GoogleConfig struct {
 	PlacesAPIKey string
 }
 
+// PlacesConfig selects where coffee shop place data comes from
+type PlacesConfig struct {
+	Provider       string // "google" (default) or "fixture"
+	FixturePath    string // JSON or GeoJSON file used by the fixture provider
+	DailyBudget    int    // Maximum Google Places calls per day across all server instances; 0 is unlimited
+	BudgetTimezone string // Timezone the daily budget resets in, matching Google's quota day
+
+	BaseURL          string        // Google Places API endpoint, overridable for tests and proxies
+	Timeout          time.Duration // Deadline for each Google Places call, including retries
+	MaxRetries       int           // Retries after a 429, 5xx or network failure; 0 disables retries
+	RetryBaseDelay   time.Duration // Backoff before the first retry, doubled for each one after
+	RetryMaxDelay    time.Duration // Longest wait between retries, including Retry-After
+	BreakerThreshold int           // Consecutive failures that open the circuit breaker; 0 disables it
+	BreakerCooldown  time.Duration // How long the open breaker fails calls fast before a trial call
+}
+
+// CacheConfig holds places cache configuration
+type CacheConfig struct {
+	Backend       string // "memory" (default), "postgres" or "none"
+	MemoryEntries int    // Maximum entries held by the in-memory LRU
+	NearbyTTL     time.Duration
+	DetailsTTL    time.Duration
+	StaleFor      time.Duration // How long past expiry an entry may be served when Google can't be called
+	PruneAfter    time.Duration // Postgres entries expired longer ago than this are deleted; must exceed StaleFor
+}
+
+// PhotoConfig holds photo proxy configuration
+type PhotoConfig struct {
+	BaseURL      string        // Prefix of photo proxy URLs sent to clients; empty makes them relative
+	SigningKey   string        // HMAC key signing photo proxy URLs; must be shared by every instance
+	CacheBackend string        // "disk" (default), "postgres" or "none"
+	CacheDir     string        // Directory used by the disk cache
+	CacheMaxMB   int           // Most megabytes the disk cache holds before evicting least recently used photos
+	CacheTTL     time.Duration // How long a fetched photo is served before fetching it again
+	MaxAge       time.Duration // Cache-Control max-age sent to clients
+}
+
+// CatalogConfig holds coffee shop catalog sync configuration
+type CatalogConfig struct {
+	SyncEnabled   bool
+	SyncInterval  time.Duration // How often the sync worker runs
+	MaxAge        time.Duration // Entries synced longer ago than this are refreshed
+	SyncBatchSize int           // Maximum entries refreshed per run
+}
+
+// SearchConfig holds defaults and bounds for nearby coffee shop searches
+type SearchConfig struct {
+	DefaultLatitude   float64 // Search center used when the client sends none
+	DefaultLongitude  float64
+	DefaultRadius     float64 // Meters
+	MinRadius         float64
+	MaxRadius         float64 // Google rejects radii above 50km
+	DefaultMaxResults int
+	MaxResults        int // Largest page of results returned at once
+}
+
+// LoggingConfig holds logger configuration
+type LoggingConfig struct {
+	Level  string // "debug", "info" (default), "warn" or "error"
+	Format string // "json" (default) or "text"
+}
+
+// ServerConfig holds HTTP server timeouts and readiness check settings
+type ServerConfig struct {
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownDelay     time.Duration // How long readiness fails before the listener closes
+	ShutdownTimeout   time.Duration // How long in-flight requests may drain after SIGTERM
+	ReadinessTimeout  time.Duration // Deadline for each readiness check
+	ReadinessPlaces   bool          // Also require the places provider to be reachable
+}
+
+// CORSConfig holds the cross-origin policy for browser clients
+type CORSConfig struct {
+	AllowedOrigins   []string // Exact origins, or patterns like https://*.example.com; "*" allows any
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string // Response headers readable by browser clients
+	AllowCredentials bool
+	MaxAge           time.Duration // How long browsers may cache preflight responses
+}
+
+// RateLimitConfig holds per-user and per-IP request limits. Limits are
+// enforced per server instance.
+type RateLimitConfig struct {
+	Enabled           bool
+	Interval          time.Duration // Window the request counts below are spread over
+	UserRequests      int           // Requests per interval for each authenticated user
+	UserBurst         int
+	IPRequests        int // Requests per interval for each IP on unauthenticated routes
+	IPBurst           int
+	AuthIPRequests    int // Requests per interval for each IP on authenticated routes, checked before the token
+	AuthIPBurst       int
+	TrustProxyHeaders bool // Take client IPs from X-Forwarded-For; only enable behind a proxy
+}
+
+// MetricsConfig holds Prometheus metrics endpoint configuration
+type MetricsConfig struct {
+	Enabled bool
+	Token   string // Bearer token required to scrape /metrics; empty leaves it open
+}
+
 // AuthConfig holds authentication configuration
 type AuthConfig struct {
-	ClerkJWTPublicKey string
+	ClerkJWTPublicKey string        // PEM public key, used when no JWKS is configured
+	JWKSURL           string        // Clerk JWKS endpoint, e.g. https://<app>.clerk.accounts.dev/.well-known/jwks.json
+	JWKSFile          string        // Local JWKS file, for development and tests
+	JWKSCacheTTL      time.Duration // How long fetched keys are trusted before refetching
+	Issuer            string        // Expected iss claim (the Clerk frontend API URL)
+	AuthorizedParties []string      // Origins allowed in the azp claim
+	ClockSkew         time.Duration // Leeway for exp, nbf and iat checks
+	WebhookSecret     string        // Svix signing secret for Clerk webhooks; empty disables them
 }
 
 // Load returns the application configuration from environment variables
@@ -35,15 +173,194 @@ func Load() *Config {
 	}
 
 	return &Config{
+		Environment: loadEnvironment(),
 		Database: DatabaseConfig{
 			ConnectionString: os.Getenv("DB_CONNECTION_STRING"),
+			AutoMigrate:      getEnvBool("DB_AUTO_MIGRATE", false),
 		},
 		Google: GoogleConfig{
 			PlacesAPIKey: os.Getenv("GOOGLE_PLACES_API_KEY"),
 		},
+		Places: PlacesConfig{
+			Provider:       getEnv("PLACES_PROVIDER", "google"),
+			FixturePath:    getEnv("PLACES_FIXTURE_PATH", "fixtures/places_la.json"),
+			DailyBudget:    getEnvInt("PLACES_DAILY_BUDGET", 0),
+			BudgetTimezone: getEnv("PLACES_BUDGET_TIMEZONE", "America/Los_Angeles"),
+
+			BaseURL:          getEnv("PLACES_BASE_URL", "https://places.googleapis.com"),
+			Timeout:          getEnvDuration("PLACES_TIMEOUT", 10*time.Second),
+			MaxRetries:       getEnvInt("PLACES_MAX_RETRIES", 2),
+			RetryBaseDelay:   getEnvDuration("PLACES_RETRY_BASE_DELAY", 200*time.Millisecond),
+			RetryMaxDelay:    getEnvDuration("PLACES_RETRY_MAX_DELAY", 5*time.Second),
+			BreakerThreshold: getEnvInt("PLACES_BREAKER_THRESHOLD", 5),
+			BreakerCooldown:  getEnvDuration("PLACES_BREAKER_COOLDOWN", 30*time.Second),
+		},
+		Cache: CacheConfig{
+			Backend:       getEnv("PLACES_CACHE_BACKEND", "memory"),
+			MemoryEntries: getEnvInt("PLACES_CACHE_MEMORY_ENTRIES", 1000),
+			NearbyTTL:     getEnvDuration("PLACES_CACHE_NEARBY_TTL", 15*time.Minute),
+			DetailsTTL:    getEnvDuration("PLACES_CACHE_DETAILS_TTL", 6*time.Hour),
+			StaleFor:      getEnvDuration("PLACES_CACHE_STALE_FOR", 24*time.Hour),
+			PruneAfter:    getEnvDuration("PLACES_CACHE_PRUNE_AFTER", 7*24*time.Hour),
+		},
+		Photos: PhotoConfig{
+			BaseURL:      getEnv("PHOTO_BASE_URL", ""),
+			SigningKey:   os.Getenv("PHOTO_SIGNING_KEY"),
+			CacheBackend: getEnv("PHOTO_CACHE_BACKEND", "disk"),
+			CacheDir:     getEnv("PHOTO_CACHE_DIR", filepath.Join(os.TempDir(), "ristretto-photos")),
+			CacheMaxMB:   getEnvInt("PHOTO_CACHE_MAX_MB", 512),
+			CacheTTL:     getEnvDuration("PHOTO_CACHE_TTL", 24*time.Hour),
+			MaxAge:       getEnvDuration("PHOTO_MAX_AGE", 24*time.Hour),
+		},
+		Catalog: CatalogConfig{
+			SyncEnabled:   getEnvBool("CATALOG_SYNC_ENABLED", true),
+			SyncInterval:  getEnvDuration("CATALOG_SYNC_INTERVAL", time.Hour),
+			MaxAge:        getEnvDuration("CATALOG_MAX_AGE", 7*24*time.Hour),
+			SyncBatchSize: getEnvInt("CATALOG_SYNC_BATCH_SIZE", 20),
+		},
 		Auth: AuthConfig{
 			ClerkJWTPublicKey: os.Getenv("CLERK_JWT_PUBLIC_KEY"),
+			JWKSURL:           os.Getenv("CLERK_JWKS_URL"),
+			JWKSFile:          os.Getenv("CLERK_JWKS_FILE"),
+			JWKSCacheTTL:      getEnvDuration("CLERK_JWKS_CACHE_TTL", time.Hour),
+			Issuer:            os.Getenv("CLERK_ISSUER"),
+			AuthorizedParties: getEnvList("CLERK_AUTHORIZED_PARTIES"),
+			ClockSkew:         getEnvDuration("CLERK_CLOCK_SKEW", 5*time.Second),
+			WebhookSecret:     os.Getenv("CLERK_WEBHOOK_SECRET"),
+		},
+		Search: SearchConfig{
+			// Downtown Los Angeles
+			DefaultLatitude:   getEnvFloat("SEARCH_DEFAULT_LAT", 34.0522),
+			DefaultLongitude:  getEnvFloat("SEARCH_DEFAULT_LNG", -118.2437),
+			DefaultRadius:     getEnvFloat("SEARCH_DEFAULT_RADIUS", 500),
+			MinRadius:         getEnvFloat("SEARCH_MIN_RADIUS", 50),
+			MaxRadius:         getEnvFloat("SEARCH_MAX_RADIUS", 50000),
+			DefaultMaxResults: getEnvInt("SEARCH_DEFAULT_MAX_RESULTS", 10),
+			MaxResults:        getEnvInt("SEARCH_MAX_RESULTS", 60),
+		},
+		Logging: LoggingConfig{
+			Level:  getEnv("LOG_LEVEL", "info"),
+			Format: getEnv("LOG_FORMAT", "json"),
+		},
+		Metrics: MetricsConfig{
+			Enabled: getEnvBool("METRICS_ENABLED", true),
+			Token:   os.Getenv("METRICS_TOKEN"),
+		},
+		Server: ServerConfig{
+			ReadTimeout:       getEnvDuration("SERVER_READ_TIMEOUT", 15*time.Second),
+			ReadHeaderTimeout: getEnvDuration("SERVER_READ_HEADER_TIMEOUT", 5*time.Second),
+			WriteTimeout:      getEnvDuration("SERVER_WRITE_TIMEOUT", 30*time.Second),
+			IdleTimeout:       getEnvDuration("SERVER_IDLE_TIMEOUT", 2*time.Minute),
+			ShutdownDelay:     getEnvDuration("SERVER_SHUTDOWN_DELAY", 0),
+			ShutdownTimeout:   getEnvDuration("SERVER_SHUTDOWN_TIMEOUT", 20*time.Second),
+			ReadinessTimeout:  getEnvDuration("READINESS_TIMEOUT", 2*time.Second),
+			ReadinessPlaces:   getEnvBool("READINESS_CHECK_PLACES", false),
+		},
+		CORS: CORSConfig{
+			// The Next.js and Expo development servers
+			AllowedOrigins:   getEnvListDefault("CORS_ALLOWED_ORIGINS", []string{"http://localhost:3000", "http://localhost:8081"}),
+			AllowedMethods:   getEnvListDefault("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
+			AllowedHeaders:   getEnvListDefault("CORS_ALLOWED_HEADERS", []string{"Authorization", "Content-Type", "Cache-Control", "X-Request-ID"}),
+			ExposedHeaders:   getEnvListDefault("CORS_EXPOSED_HEADERS", []string{"X-Request-ID", "X-Data-Source"}),
+			AllowCredentials: getEnvBool("CORS_ALLOW_CREDENTIALS", true),
+			MaxAge:           getEnvDuration("CORS_MAX_AGE", 10*time.Minute),
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:           getEnvBool("RATE_LIMIT_ENABLED", true),
+			Interval:          getEnvDuration("RATE_LIMIT_INTERVAL", time.Minute),
+			UserRequests:      getEnvInt("RATE_LIMIT_USER_REQUESTS", 60),
+			UserBurst:         getEnvInt("RATE_LIMIT_USER_BURST", 20),
+			IPRequests:        getEnvInt("RATE_LIMIT_IP_REQUESTS", 30),
+			IPBurst:           getEnvInt("RATE_LIMIT_IP_BURST", 10),
+			AuthIPRequests:    getEnvInt("RATE_LIMIT_AUTH_IP_REQUESTS", 300),
+			AuthIPBurst:       getEnvInt("RATE_LIMIT_AUTH_IP_BURST", 100),
+			TrustProxyHeaders: getEnvBool("TRUST_PROXY_HEADERS", false),
 		},
 		ServerPort: port,
 	}
 }
+
+// AllowMockFallback reports whether handlers may serve mock data when the
+// places provider fails. Mock data is never served in production.
+func (c *Config) AllowMockFallback() bool {
+	return c.Environment == EnvDevelopment || c.Environment == EnvStaging
+}
+
+// loadEnvironment reads APP_ENV, defaulting to production so that a missing
+// or mistyped value never enables development behavior on a real deployment
+func loadEnvironment() Environment {
+	switch env := Environment(os.Getenv("APP_ENV")); env {
+	case EnvDevelopment, EnvStaging, EnvProduction:
+		return env
+	case "":
+		slog.Info("APP_ENV not set, defaulting to production")
+		return EnvProduction
+	default:
+		slog.Warn("Unknown APP_ENV, defaulting to production", "app_env", string(env))
+		return EnvProduction
+	}
+}
+
+// getEnv reads an environment variable, returning fallback if it is unset
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getEnvBool reads a boolean environment variable, returning fallback if it is unset or invalid
+func getEnvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getEnvInt reads an integer environment variable, returning fallback if it is unset or invalid
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getEnvFloat reads a float environment variable, returning fallback if it is unset or invalid
+func getEnvFloat(key string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getEnvList reads a comma-separated environment variable, skipping empty items
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getEnvListDefault reads a comma-separated environment variable, returning
+// fallback if it is unset or empty
+func getEnvListDefault(key string, fallback []string) []string {
+	if values := getEnvList(key); len(values) > 0 {
+		return values
+	}
+	return fallback
+}
+
+// getEnvDuration reads a duration environment variable such as "15m", returning fallback if it is unset or invalid
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// GetCacheEntry retrieves a cached value and its expiry time
+func (db *DB) GetCacheEntry(ctx context.Context, key string) ([]byte, time.Time, error) {
+	var (
+		value     []byte
+		expiresAt time.Time
+	)
+
+	err := db.QueryRowContext(ctx, `
+		SELECT value, expires_at
+		FROM places_cache
+		WHERE key = $1
+	`, key).Scan(&value, &expiresAt)
+
+	return value, expiresAt, err
+}
+
+// DeleteExpiredCacheEntries deletes cached values that expired before cutoff
+// and returns how many were deleted
+func (db *DB) DeleteExpiredCacheEntries(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := db.ExecContext(ctx, `
+		DELETE FROM places_cache
+		WHERE expires_at < $1
+	`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// SetCacheEntry stores a cached value, replacing any existing entry for the key
+func (db *DB) SetCacheEntry(ctx context.Context, key string, value []byte, expiresAt time.Time) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO places_cache (key, value, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET
+			value = EXCLUDED.value,
+			expires_at = EXCLUDED.expires_at,
+			updated_at = NOW()
+	`, key, value, expiresAt)
+	return err
+}
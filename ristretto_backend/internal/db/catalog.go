@@ -0,0 +1,248 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
+)
+
+// execer is implemented by both *DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// catalogColumns is the column list shared by catalog queries
+const catalogColumns = `
+	place_id, name, latitude, longitude, address, phone_number, website, google_maps_uri,
+	rating, price_level, opening_hours, photos, last_synced_at, not_found_at`
+
+// ensureCoffeeShop creates a stub catalog row for a place if none exists, so
+// that rows referencing the place satisfy their foreign key
+func ensureCoffeeShop(exec execer, placeID, name string, latitude, longitude *float64) error {
+	_, err := exec.Exec(`
+		INSERT INTO coffee_shops (place_id, name, latitude, longitude)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (place_id) DO NOTHING
+	`, placeID, name, latitude, longitude)
+	return err
+}
+
+// GetCoffeeShop retrieves a coffee shop from the local catalog
+func (db *DB) GetCoffeeShop(placeID string) (*models.CatalogCoffeeShop, error) {
+	var (
+		shop         models.CatalogCoffeeShop
+		latitude     sql.NullFloat64
+		longitude    sql.NullFloat64
+		rating       sql.NullFloat64
+		priceLevel   sql.NullInt64
+		openingHours []byte
+		photos       []byte
+		lastSyncedAt sql.NullTime
+		notFoundAt   sql.NullTime
+	)
+
+	err := db.QueryRow(`
+		SELECT `+catalogColumns+`
+		FROM coffee_shops
+		WHERE place_id = $1
+	`, placeID).Scan(
+		&shop.PlaceID, &shop.Name, &latitude, &longitude,
+		&shop.Address, &shop.PhoneNumber, &shop.Website, &shop.GoogleMapsURI,
+		&rating, &priceLevel, &openingHours, &photos, &lastSyncedAt, &notFoundAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	shop.Latitude = latitude.Float64
+	shop.Longitude = longitude.Float64
+	shop.Rating = rating.Float64
+	shop.PriceLevel = int(priceLevel.Int64)
+	if lastSyncedAt.Valid {
+		shop.LastSyncedAt = &lastSyncedAt.Time
+	}
+	if notFoundAt.Valid {
+		shop.NotFoundAt = &notFoundAt.Time
+	}
+
+	if len(openingHours) > 0 {
+		if err := json.Unmarshal(openingHours, &shop.OpeningHours); err != nil {
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(photos, &shop.Photos); err != nil {
+		return nil, err
+	}
+
+	return &shop, nil
+}
+
+// UpsertCoffeeShop stores a coffee shop synced from Places, overwriting any existing row
+func (db *DB) UpsertCoffeeShop(shop models.CatalogCoffeeShop) error {
+	return db.saveCoffeeShop(shop, "")
+}
+
+// FillCoffeeShop stores a coffee shop synced from Places only if the catalog
+// has no row for it yet or has just an unsynced stub. Synced rows are left
+// for the catalog sync worker to refresh.
+func (db *DB) FillCoffeeShop(shop models.CatalogCoffeeShop) error {
+	return db.saveCoffeeShop(shop, "WHERE coffee_shops.last_synced_at IS NULL")
+}
+
+// saveCoffeeShop upserts a catalog row, applying the optional conflict condition
+func (db *DB) saveCoffeeShop(shop models.CatalogCoffeeShop, conflictCondition string) error {
+	var openingHours sql.NullString
+	if shop.OpeningHours != nil {
+		data, err := json.Marshal(shop.OpeningHours)
+		if err != nil {
+			return err
+		}
+		openingHours = sql.NullString{String: string(data), Valid: true}
+	}
+
+	photos := shop.Photos
+	if photos == nil {
+		photos = []*models.Photo{}
+	}
+	photosJSON, err := json.Marshal(photos)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO coffee_shops (
+			place_id, name, latitude, longitude, address, phone_number, website, google_maps_uri,
+			rating, price_level, opening_hours, photos, last_synced_at, sync_attempted_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9::DOUBLE PRECISION, 0), NULLIF($10::SMALLINT, 0), $11, $12, NOW(), NOW())
+		ON CONFLICT (place_id) DO UPDATE SET
+			name = EXCLUDED.name,
+			latitude = EXCLUDED.latitude,
+			longitude = EXCLUDED.longitude,
+			address = EXCLUDED.address,
+			phone_number = EXCLUDED.phone_number,
+			website = EXCLUDED.website,
+			google_maps_uri = EXCLUDED.google_maps_uri,
+			rating = EXCLUDED.rating,
+			price_level = EXCLUDED.price_level,
+			opening_hours = EXCLUDED.opening_hours,
+			photos = EXCLUDED.photos,
+			last_synced_at = EXCLUDED.last_synced_at,
+			not_found_at = NULL,
+			updated_at = NOW()
+		`+conflictCondition,
+		shop.PlaceID, shop.Name, shop.Latitude, shop.Longitude,
+		shop.Address, shop.PhoneNumber, shop.Website, shop.GoogleMapsURI,
+		shop.Rating, shop.PriceLevel, openingHours, string(photosJSON),
+	)
+	return err
+}
+
+// MarkCoffeeShopSyncAttempt records a failed sync attempt so the sync worker
+// moves on to other entries instead of retrying the same place every run
+func (db *DB) MarkCoffeeShopSyncAttempt(placeID string) error {
+	_, err := db.Exec(`
+		UPDATE coffee_shops
+		SET sync_attempted_at = NOW()
+		WHERE place_id = $1
+	`, placeID)
+	return err
+}
+
+// MarkCoffeeShopNotFound records that Places no longer knows a place, so the
+// sync worker stops retrying it
+func (db *DB) MarkCoffeeShopNotFound(placeID string) error {
+	_, err := db.Exec(`
+		UPDATE coffee_shops
+		SET not_found_at = NOW(), sync_attempted_at = NOW()
+		WHERE place_id = $1
+	`, placeID)
+	return err
+}
+
+// GetStaleCoffeeShops retrieves IDs of catalog entries never synced or last
+// synced before syncedBefore, skipping entries attempted since attemptedBefore
+// and places Places no longer knows. Least recently attempted entries come first.
+func (db *DB) GetStaleCoffeeShops(syncedBefore, attemptedBefore time.Time, limit int) ([]string, error) {
+	placeIDs := []string{}
+
+	rows, err := db.Query(`
+		SELECT place_id
+		FROM coffee_shops
+		WHERE (last_synced_at IS NULL OR last_synced_at < $1)
+			AND (sync_attempted_at IS NULL OR sync_attempted_at < $2)
+			AND not_found_at IS NULL
+		ORDER BY sync_attempted_at NULLS FIRST
+		LIMIT $3
+	`, syncedBefore, attemptedBefore, limit)
+	if err != nil {
+		return placeIDs, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var placeID string
+		if err := rows.Scan(&placeID); err != nil {
+			return placeIDs, err
+		}
+		placeIDs = append(placeIDs, placeID)
+	}
+
+	return placeIDs, rows.Err()
+}
+
+// SearchCoffeeShops searches catalog entries by name and address. With prefix
+// set, the last word of the query matches as a prefix for as-you-type search.
+// Unsynced stubs without a location are excluded.
+func (db *DB) SearchCoffeeShops(query string, prefix bool, limit int) ([]models.CatalogCoffeeShop, error) {
+	shops := []models.CatalogCoffeeShop{}
+
+	tsQuery := buildTSQuery(query, prefix)
+	if tsQuery == "" {
+		return shops, nil
+	}
+
+	rows, err := db.Query(`
+		SELECT place_id, name, latitude, longitude, address
+		FROM coffee_shops
+		WHERE latitude IS NOT NULL
+			AND (search_vector @@ to_tsquery('simple', $1) OR name % $2)
+		ORDER BY GREATEST(ts_rank(search_vector, to_tsquery('simple', $1)), similarity(name, $2)) DESC, name
+		LIMIT $3
+	`, tsQuery, query, limit)
+	if err != nil {
+		return shops, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var shop models.CatalogCoffeeShop
+		if err := rows.Scan(&shop.PlaceID, &shop.Name, &shop.Latitude, &shop.Longitude, &shop.Address); err != nil {
+			return shops, err
+		}
+		shops = append(shops, shop)
+	}
+
+	return shops, rows.Err()
+}
+
+// buildTSQuery turns free text into a tsquery that requires every word,
+// stripping tsquery operators so user input can't produce a syntax error
+func buildTSQuery(query string, prefix bool) string {
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return ""
+	}
+
+	if prefix {
+		words[len(words)-1] += ":*"
+	}
+
+	return strings.Join(words, " & ")
+}
@@ -2,9 +2,11 @@ package db
 
 import (
 	"database/sql"
-	"log"
+	"log/slog"
 
 	_ "github.com/lib/pq" // PostgreSQL driver
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
 )
 
 // DB is a wrapper around sql.DB with additional methods
@@ -25,15 +27,18 @@ func Connect(connStr string) (*DB, error) {
 		return nil, err
 	}
 
-	log.Println("Successfully connected to database")
+	slog.Info("Connected to database")
 	return &DB{db}, nil
 }
 
-// GetUserByClerkID retrieves a user by their Clerk ID
-func (db *DB) GetUserByClerkID(clerkID string) (int, error) {
-	var userID int
-	err := db.QueryRow("SELECT id FROM users WHERE clerk_id = $1", clerkID).Scan(&userID)
-	return userID, err
+// GetUserByClerkID retrieves a user's ID and role by their Clerk ID
+func (db *DB) GetUserByClerkID(clerkID string) (int, string, error) {
+	var (
+		userID int
+		role   string
+	)
+	err := db.QueryRow("SELECT id, role FROM users WHERE clerk_id = $1", clerkID).Scan(&userID, &role)
+	return userID, role, err
 }
 
 // CreateUser creates a new user in the database
@@ -46,6 +51,88 @@ func (db *DB) CreateUser(clerkID, email, firstName, lastName string) (int, error
 	return userID, err
 }
 
+// UpsertUser creates a user or updates the email and name of an existing one
+func (db *DB) UpsertUser(clerkID, email, firstName, lastName string) (int, error) {
+	var userID int
+	err := db.QueryRow(`
+		INSERT INTO users (clerk_id, email, first_name, last_name)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (clerk_id) DO UPDATE SET
+			email = EXCLUDED.email,
+			first_name = EXCLUDED.first_name,
+			last_name = EXCLUDED.last_name,
+			updated_at = NOW()
+		RETURNING id
+	`, clerkID, email, firstName, lastName).Scan(&userID)
+	return userID, err
+}
+
+// UpdateUserByClerkID updates the email and name of an existing user. Unknown
+// users are left alone, so a late update can't recreate a deleted account.
+func (db *DB) UpdateUserByClerkID(clerkID, email, firstName, lastName string) (int64, error) {
+	result, err := db.Exec(`
+		UPDATE users
+		SET email = $2, first_name = $3, last_name = $4, updated_at = NOW()
+		WHERE clerk_id = $1
+	`, clerkID, email, firstName, lastName)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteUserByClerkID deletes a user along with their favorites and visits.
+// Their reviews are kept but detached from the account, leaving ratings intact.
+func (db *DB) DeleteUserByClerkID(clerkID string) (int64, error) {
+	result, err := db.Exec("DELETE FROM users WHERE clerk_id = $1", clerkID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ListUsers retrieves a page of users, oldest first
+func (db *DB) ListUsers(limit, offset int) ([]models.User, error) {
+	users := []models.User{}
+
+	rows, err := db.Query(`
+		SELECT id, clerk_id, email, first_name, last_name, role, created_at, updated_at
+		FROM users
+		ORDER BY id
+		LIMIT $1 OFFSET $2
+	`, limit, offset)
+	if err != nil {
+		return users, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(
+			&user.ID, &user.ClerkID, &user.Email, &user.FirstName, &user.LastName,
+			&user.Role, &user.CreatedAt, &user.UpdatedAt,
+		); err != nil {
+			return users, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// SetUserRole changes a user's role
+func (db *DB) SetUserRole(userID int, role string) (int64, error) {
+	result, err := db.Exec(`
+		UPDATE users
+		SET role = $2, updated_at = NOW()
+		WHERE id = $1
+	`, userID, role)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 // GetUserFavorites retrieves a user's favorite coffee shop IDs
 func (db *DB) GetUserFavorites(userID int) (map[string]bool, error) {
 	favorites := make(map[string]bool)
@@ -75,17 +162,18 @@ func (db *DB) GetUserProfile(userID int) (map[string]interface{}, error) {
 		email     string
 		firstName string
 		lastName  string
+		role      string
 		createdAt string
 		updatedAt string
 	)
 
 	err := db.QueryRow(`
-		SELECT id, clerk_id, email, first_name, last_name, created_at, updated_at 
+		SELECT id, clerk_id, email, first_name, last_name, role, created_at, updated_at 
 		FROM users 
 		WHERE id = $1
 	`, userID).Scan(
 		&id, &clerkID, &email,
-		&firstName, &lastName,
+		&firstName, &lastName, &role,
 		&createdAt, &updatedAt,
 	)
 
@@ -99,6 +187,7 @@ func (db *DB) GetUserProfile(userID int) (map[string]interface{}, error) {
 		"email":     email,
 		"firstName": firstName,
 		"lastName":  lastName,
+		"role":      role,
 		"createdAt": createdAt,
 		"updatedAt": updatedAt,
 	}, nil
@@ -145,12 +234,26 @@ func (db *DB) GetFavorites(userID int) ([]map[string]interface{}, error) {
 
 // AddFavorite adds a coffee shop to a user's favorites
 func (db *DB) AddFavorite(userID int, placeID, name string, latitude, longitude float64) error {
-	_, err := db.Exec(`
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := ensureCoffeeShop(tx, placeID, name, &latitude, &longitude); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
 		INSERT INTO favorite_coffee_shops (user_id, place_id, name, latitude, longitude) 
 		VALUES ($1, $2, $3, $4, $5)
 		ON CONFLICT (user_id, place_id) DO NOTHING
 	`, userID, placeID, name, latitude, longitude)
-	return err
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // RemoveFavorite removes a coffee shop from a user's favorites
@@ -204,11 +307,46 @@ func (db *DB) GetVisits(userID int) ([]map[string]interface{}, error) {
 	return visits, rows.Err()
 }
 
+// GetUserVisitedPlaces retrieves the IDs of all coffee shops a user has visited
+func (db *DB) GetUserVisitedPlaces(userID int) (map[string]bool, error) {
+	visited := make(map[string]bool)
+
+	rows, err := db.Query("SELECT DISTINCT place_id FROM visits WHERE user_id = $1", userID)
+	if err != nil {
+		return visited, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var placeID string
+		if err := rows.Scan(&placeID); err != nil {
+			return visited, err
+		}
+		visited[placeID] = true
+	}
+
+	return visited, rows.Err()
+}
+
 // AddVisit records a visit to a coffee shop
 func (db *DB) AddVisit(userID int, placeID, name string) error {
-	_, err := db.Exec(`
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := ensureCoffeeShop(tx, placeID, name, nil, nil); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
 		INSERT INTO visits (user_id, place_id, name) 
 		VALUES ($1, $2, $3)
 	`, userID, placeID, name)
-	return err
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
@@ -0,0 +1,318 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID is the Postgres advisory lock key held while migrating, so
+// that several server instances starting at once don't race each other
+const migrationLockID = 72_837_041
+
+// migrationFilePattern matches files like 0001_initial_schema.up.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is a single versioned schema change
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // SHA-256 of the up script
+}
+
+// MigrationStatus describes whether a migration has been applied
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt string
+}
+
+// LoadMigrations reads the embedded migrations ordered by version
+func LoadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		matches := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(matches[1])
+		contents, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = m
+		} else if m.Name != matches[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names: %s and %s", version, m.Name, matches[2])
+		}
+
+		if matches[3] == "up" {
+			m.Up = string(contents)
+			sum := sha256.Sum256(contents)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s must have both up and down scripts", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// appliedMigration is a row of the schema_version table
+type appliedMigration struct {
+	checksum  string
+	appliedAt string
+}
+
+// Migrate applies all pending migrations in order. With dryRun set, the
+// pending migrations are returned without being applied.
+func (db *DB) Migrate(dryRun bool) ([]Migration, error) {
+	var pending []Migration
+
+	err := db.withMigrationLock(func(conn *sql.Conn, migrations []Migration, applied map[int]appliedMigration) error {
+		for _, m := range migrations {
+			if _, ok := applied[m.Version]; !ok {
+				pending = append(pending, m)
+			}
+		}
+
+		if dryRun {
+			return nil
+		}
+
+		for _, m := range pending {
+			slog.Info("Applying migration", "version", m.Version, "name", m.Name)
+			err := runInTx(conn, m.Up, `
+				INSERT INTO schema_version (version, name, checksum) VALUES ($1, $2, $3)
+			`, m.Version, m.Name, m.Checksum)
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s failed: %w", m.Version, m.Name, err)
+			}
+		}
+		return nil
+	})
+
+	return pending, err
+}
+
+// Rollback reverts the given number of most recently applied migrations. With
+// dryRun set, the migrations that would be reverted are returned without
+// being reverted.
+func (db *DB) Rollback(steps int, dryRun bool) ([]Migration, error) {
+	var reverted []Migration
+
+	err := db.withMigrationLock(func(conn *sql.Conn, migrations []Migration, applied map[int]appliedMigration) error {
+		for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			if _, ok := applied[migrations[i].Version]; ok {
+				reverted = append(reverted, migrations[i])
+			}
+		}
+
+		if dryRun {
+			return nil
+		}
+
+		for _, m := range reverted {
+			slog.Info("Reverting migration", "version", m.Version, "name", m.Name)
+			err := runInTx(conn, m.Down, `
+				DELETE FROM schema_version WHERE version = $1
+			`, m.Version)
+			if err != nil {
+				return fmt.Errorf("rollback of %04d_%s failed: %w", m.Version, m.Name, err)
+			}
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+// MigrationStatus reports every known migration and whether it has been
+// applied. It only reads: it doesn't wait for the migration lock, and a
+// database without a schema_version table has no migrations applied.
+func (db *DB) MigrationStatus() ([]MigrationStatus, error) {
+	ctx := context.Background()
+
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var exists bool
+	if err := conn.QueryRowContext(ctx, "SELECT to_regclass('schema_version') IS NOT NULL").Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to look up schema_version table: %w", err)
+	}
+
+	applied := make(map[int]appliedMigration)
+	if exists {
+		if applied, err = appliedMigrations(ctx, conn); err != nil {
+			return nil, err
+		}
+		if err := verifyChecksums(migrations, applied); err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		a, ok := applied[m.Version]
+		statuses = append(statuses, MigrationStatus{
+			Migration: m,
+			Applied:   ok,
+			AppliedAt: a.appliedAt,
+		})
+	}
+
+	return statuses, nil
+}
+
+// withMigrationLock takes the migration advisory lock, ensures the
+// schema_version table exists, verifies applied checksums and then calls fn
+func (db *DB) withMigrationLock(fn func(*sql.Conn, []Migration, map[int]appliedMigration) error) error {
+	ctx := context.Background()
+
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return err
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationLockID)
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_version (
+			version    INTEGER PRIMARY KEY,
+			name       TEXT NOT NULL,
+			checksum   TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_version table: %w", err)
+	}
+
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	if err := verifyChecksums(migrations, applied); err != nil {
+		return err
+	}
+
+	return fn(conn, migrations, applied)
+}
+
+// appliedMigrations reads the schema_version table
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int]appliedMigration, error) {
+	applied := make(map[int]appliedMigration)
+
+	rows, err := conn.QueryContext(ctx, "SELECT version, checksum, applied_at FROM schema_version")
+	if err != nil {
+		return applied, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			version int
+			a       appliedMigration
+		)
+		if err := rows.Scan(&version, &a.checksum, &a.appliedAt); err != nil {
+			return applied, err
+		}
+		applied[version] = a
+	}
+
+	return applied, rows.Err()
+}
+
+// verifyChecksums fails if an applied migration was edited after it ran or is
+// unknown to this binary
+func verifyChecksums(migrations []Migration, applied map[int]appliedMigration) error {
+	known := make(map[int]Migration, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = m
+	}
+
+	for version, a := range applied {
+		m, ok := known[version]
+		if !ok {
+			return fmt.Errorf("database has migration %04d applied which is unknown to this binary", version)
+		}
+		if m.Checksum != a.checksum {
+			return fmt.Errorf("checksum mismatch for applied migration %04d_%s: database has %s, binary has %s",
+				m.Version, m.Name, a.checksum, m.Checksum)
+		}
+	}
+
+	return nil
+}
+
+// runInTx runs a migration script and its schema_version bookkeeping in one transaction
+func runInTx(conn *sql.Conn, script, bookkeeping string, args ...interface{}) error {
+	ctx := context.Background()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, bookkeeping, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
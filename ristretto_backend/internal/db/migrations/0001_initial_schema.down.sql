@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS visits;
+DROP TABLE IF EXISTS favorite_coffee_shops;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,33 @@
+-- Users authenticated through Clerk
+CREATE TABLE IF NOT EXISTS users (
+    id         SERIAL PRIMARY KEY,
+    clerk_id   TEXT NOT NULL UNIQUE,
+    email      TEXT NOT NULL DEFAULT '',
+    first_name TEXT NOT NULL DEFAULT '',
+    last_name  TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Coffee shops a user has favorited, with a snapshot of name and location
+CREATE TABLE IF NOT EXISTS favorite_coffee_shops (
+    id         SERIAL PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    place_id   TEXT NOT NULL,
+    name       TEXT NOT NULL,
+    latitude   DOUBLE PRECISION NOT NULL,
+    longitude  DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, place_id)
+);
+
+-- Coffee shop visits recorded by a user
+CREATE TABLE IF NOT EXISTS visits (
+    id         SERIAL PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    place_id   TEXT NOT NULL,
+    name       TEXT NOT NULL,
+    visited_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS visits_user_id_visited_at_idx ON visits (user_id, visited_at DESC);
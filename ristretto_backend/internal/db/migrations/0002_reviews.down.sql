@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS review_aggregates;
+DROP TABLE IF EXISTS reviews;
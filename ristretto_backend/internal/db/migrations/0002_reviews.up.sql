@@ -0,0 +1,41 @@
+-- Multi-metric coffee shop reviews. A NULL score means the metric was not rated.
+CREATE TABLE reviews (
+    id          SERIAL PRIMARY KEY,
+    user_id     INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    place_id    TEXT NOT NULL,
+    espresso    SMALLINT CHECK (espresso BETWEEN 1 AND 5),
+    milk_drinks SMALLINT CHECK (milk_drinks BETWEEN 1 AND 5),
+    ambiance    SMALLINT CHECK (ambiance BETWEEN 1 AND 5),
+    seating     SMALLINT CHECK (seating BETWEEN 1 AND 5),
+    wifi        SMALLINT CHECK (wifi BETWEEN 1 AND 5),
+    value       SMALLINT CHECK (value BETWEEN 1 AND 5),
+    service     SMALLINT CHECK (service BETWEEN 1 AND 5),
+    body        TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, place_id)
+);
+
+CREATE INDEX reviews_place_id_created_at_idx ON reviews (place_id, created_at DESC);
+
+-- Per-place review totals, maintained incrementally by the review write paths
+CREATE TABLE review_aggregates (
+    place_id          TEXT PRIMARY KEY,
+    review_count      INTEGER NOT NULL DEFAULT 0,
+    overall_sum       DOUBLE PRECISION NOT NULL DEFAULT 0,
+    espresso_sum      INTEGER NOT NULL DEFAULT 0,
+    espresso_count    INTEGER NOT NULL DEFAULT 0,
+    milk_drinks_sum   INTEGER NOT NULL DEFAULT 0,
+    milk_drinks_count INTEGER NOT NULL DEFAULT 0,
+    ambiance_sum      INTEGER NOT NULL DEFAULT 0,
+    ambiance_count    INTEGER NOT NULL DEFAULT 0,
+    seating_sum       INTEGER NOT NULL DEFAULT 0,
+    seating_count     INTEGER NOT NULL DEFAULT 0,
+    wifi_sum          INTEGER NOT NULL DEFAULT 0,
+    wifi_count        INTEGER NOT NULL DEFAULT 0,
+    value_sum         INTEGER NOT NULL DEFAULT 0,
+    value_count       INTEGER NOT NULL DEFAULT 0,
+    service_sum       INTEGER NOT NULL DEFAULT 0,
+    service_count     INTEGER NOT NULL DEFAULT 0,
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,9 @@
+-- Shared read-through cache for Google Places responses
+CREATE TABLE places_cache (
+    key        TEXT PRIMARY KEY,
+    value      BYTEA NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX places_cache_expires_at_idx ON places_cache (expires_at);
@@ -0,0 +1,6 @@
+ALTER TABLE review_aggregates DROP CONSTRAINT IF EXISTS review_aggregates_place_id_fkey;
+ALTER TABLE reviews DROP CONSTRAINT IF EXISTS reviews_place_id_fkey;
+ALTER TABLE visits DROP CONSTRAINT IF EXISTS visits_place_id_fkey;
+ALTER TABLE favorite_coffee_shops DROP CONSTRAINT IF EXISTS favorite_coffee_shops_place_id_fkey;
+
+DROP TABLE IF EXISTS coffee_shops;
@@ -0,0 +1,53 @@
+-- Local catalog of coffee shops keyed by Google place ID. Rows created from a
+-- favorite, visit or review before the shop was ever fetched are stubs with a
+-- NULL last_synced_at until the catalog sync worker fills them in.
+CREATE TABLE coffee_shops (
+    place_id          TEXT PRIMARY KEY,
+    name              TEXT NOT NULL DEFAULT '',
+    latitude          DOUBLE PRECISION,
+    longitude         DOUBLE PRECISION,
+    address           TEXT NOT NULL DEFAULT '',
+    phone_number      TEXT NOT NULL DEFAULT '',
+    website           TEXT NOT NULL DEFAULT '',
+    google_maps_uri   TEXT NOT NULL DEFAULT '',
+    rating            DOUBLE PRECISION,
+    price_level       SMALLINT,
+    opening_hours     JSONB,
+    photos            JSONB NOT NULL DEFAULT '[]',
+    last_synced_at    TIMESTAMPTZ,
+    sync_attempted_at TIMESTAMPTZ,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX coffee_shops_sync_attempted_at_idx ON coffee_shops (sync_attempted_at NULLS FIRST);
+
+-- Backfill stubs for every place already referenced
+INSERT INTO coffee_shops (place_id, name, latitude, longitude)
+SELECT DISTINCT ON (place_id) place_id, name, latitude, longitude
+FROM favorite_coffee_shops
+ORDER BY place_id, created_at DESC
+ON CONFLICT (place_id) DO NOTHING;
+
+INSERT INTO coffee_shops (place_id, name)
+SELECT DISTINCT ON (place_id) place_id, name
+FROM visits
+ORDER BY place_id, visited_at DESC
+ON CONFLICT (place_id) DO NOTHING;
+
+INSERT INTO coffee_shops (place_id)
+SELECT DISTINCT place_id FROM reviews
+ON CONFLICT (place_id) DO NOTHING;
+
+INSERT INTO coffee_shops (place_id)
+SELECT place_id FROM review_aggregates
+ON CONFLICT (place_id) DO NOTHING;
+
+ALTER TABLE favorite_coffee_shops
+    ADD CONSTRAINT favorite_coffee_shops_place_id_fkey FOREIGN KEY (place_id) REFERENCES coffee_shops (place_id);
+ALTER TABLE visits
+    ADD CONSTRAINT visits_place_id_fkey FOREIGN KEY (place_id) REFERENCES coffee_shops (place_id);
+ALTER TABLE reviews
+    ADD CONSTRAINT reviews_place_id_fkey FOREIGN KEY (place_id) REFERENCES coffee_shops (place_id);
+ALTER TABLE review_aggregates
+    ADD CONSTRAINT review_aggregates_place_id_fkey FOREIGN KEY (place_id) REFERENCES coffee_shops (place_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS coffee_shops_name_trgm_idx;
+DROP INDEX IF EXISTS coffee_shops_search_vector_idx;
+ALTER TABLE coffee_shops DROP COLUMN IF EXISTS search_vector;
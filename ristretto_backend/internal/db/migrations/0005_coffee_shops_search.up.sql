@@ -0,0 +1,11 @@
+-- Full-text and trigram indexes for searching the local coffee shop catalog
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE coffee_shops
+    ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(address, '')), 'B')
+    ) STORED;
+
+CREATE INDEX coffee_shops_search_vector_idx ON coffee_shops USING GIN (search_vector);
+CREATE INDEX coffee_shops_name_trgm_idx ON coffee_shops USING GIN (name gin_trgm_ops);
@@ -0,0 +1,35 @@
+-- Anonymized reviews have no author to restore, so they are removed and the
+-- aggregates rebuilt from the remaining reviews
+DELETE FROM reviews WHERE user_id IS NULL;
+
+TRUNCATE review_aggregates;
+INSERT INTO review_aggregates (
+    place_id, review_count, overall_sum,
+    espresso_sum, espresso_count, milk_drinks_sum, milk_drinks_count,
+    ambiance_sum, ambiance_count, seating_sum, seating_count,
+    wifi_sum, wifi_count, value_sum, value_count, service_sum, service_count
+)
+SELECT
+    place_id,
+    COUNT(*),
+    SUM(
+        (COALESCE(espresso, 0) + COALESCE(milk_drinks, 0) + COALESCE(ambiance, 0) + COALESCE(seating, 0)
+            + COALESCE(wifi, 0) + COALESCE(value, 0) + COALESCE(service, 0))::DOUBLE PRECISION
+        / NULLIF(num_nonnulls(espresso, milk_drinks, ambiance, seating, wifi, value, service), 0)
+    ),
+    COALESCE(SUM(espresso), 0), COUNT(espresso),
+    COALESCE(SUM(milk_drinks), 0), COUNT(milk_drinks),
+    COALESCE(SUM(ambiance), 0), COUNT(ambiance),
+    COALESCE(SUM(seating), 0), COUNT(seating),
+    COALESCE(SUM(wifi), 0), COUNT(wifi),
+    COALESCE(SUM(value), 0), COUNT(value),
+    COALESCE(SUM(service), 0), COUNT(service)
+FROM reviews
+GROUP BY place_id;
+
+ALTER TABLE reviews DROP CONSTRAINT reviews_user_id_fkey;
+ALTER TABLE reviews
+    ADD CONSTRAINT reviews_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+
+ALTER TABLE reviews ALTER COLUMN user_id SET NOT NULL;
@@ -0,0 +1,8 @@
+-- Reviews outlive their authors: when a user is deleted their reviews are
+-- kept, detached from the account, so coffee shop ratings don't change
+ALTER TABLE reviews ALTER COLUMN user_id DROP NOT NULL;
+
+ALTER TABLE reviews DROP CONSTRAINT reviews_user_id_fkey;
+ALTER TABLE reviews
+    ADD CONSTRAINT reviews_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL;
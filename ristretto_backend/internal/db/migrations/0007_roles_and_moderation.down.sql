@@ -0,0 +1,9 @@
+-- Hidden reviews are already excluded from review_aggregates, so removing
+-- them keeps the aggregates consistent once the hidden flag is gone
+DELETE FROM reviews WHERE hidden_at IS NOT NULL;
+
+ALTER TABLE reviews
+    DROP COLUMN IF EXISTS hidden_by,
+    DROP COLUMN IF EXISTS hidden_at;
+
+ALTER TABLE users DROP COLUMN IF EXISTS role;
@@ -0,0 +1,10 @@
+-- User roles. Promote the first admin with:
+--   UPDATE users SET role = 'admin' WHERE email = '...';
+ALTER TABLE users
+    ADD COLUMN role TEXT NOT NULL DEFAULT 'user'
+    CHECK (role IN ('user', 'moderator', 'admin'));
+
+-- Reviews hidden by a moderator are excluded from listings and review_aggregates
+ALTER TABLE reviews
+    ADD COLUMN hidden_at TIMESTAMPTZ,
+    ADD COLUMN hidden_by INTEGER REFERENCES users (id) ON DELETE SET NULL;
@@ -0,0 +1,7 @@
+-- Google Places calls made per day across every server instance, checked
+-- against PLACES_DAILY_BUDGET. The day is taken in PLACES_BUDGET_TIMEZONE.
+CREATE TABLE places_budget (
+    day        DATE PRIMARY KEY,
+    calls      INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1 @@
+ALTER TABLE coffee_shops DROP COLUMN IF EXISTS not_found_at;
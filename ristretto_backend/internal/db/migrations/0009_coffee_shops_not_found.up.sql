@@ -0,0 +1,4 @@
+-- Set when Google Places reports a catalog entry's place no longer exists.
+-- The catalog sync worker stops retrying these rows; favorites, visits and
+-- reviews that reference them keep their foreign keys.
+ALTER TABLE coffee_shops ADD COLUMN not_found_at TIMESTAMPTZ;
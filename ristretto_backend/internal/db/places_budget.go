@@ -0,0 +1,27 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// TakePlacesCall counts one Google Places call on day (YYYY-MM-DD), unless
+// limit calls were already made that day. The check and increment are a
+// single statement, so concurrent instances can't overspend.
+func (db *DB) TakePlacesCall(ctx context.Context, day string, limit int) (bool, error) {
+	var calls int
+	err := db.QueryRowContext(ctx, `
+		INSERT INTO places_budget (day, calls)
+		VALUES ($1, 1)
+		ON CONFLICT (day) DO UPDATE SET
+			calls = places_budget.calls + 1,
+			updated_at = NOW()
+		WHERE places_budget.calls < $2
+		RETURNING calls
+	`, day, limit).Scan(&calls)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
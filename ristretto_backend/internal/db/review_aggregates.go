@@ -0,0 +1,164 @@
+package db
+
+import (
+	"database/sql"
+	"math"
+
+	"github.com/lib/pq"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
+)
+
+// Bayesian prior used to weight the overall score of shops with few reviews.
+// A shop with no reviews scores the prior mean, and each real review pulls the
+// score towards the shop's own average.
+const (
+	bayesianPriorMean   = 3.0
+	bayesianPriorWeight = 5.0
+)
+
+// applyReviewDelta adds (sign = 1) or removes (sign = -1) a review's scores
+// to the incrementally maintained review_aggregates row for the place.
+func applyReviewDelta(tx *sql.Tx, placeID string, scores models.ReviewScores, sign int) error {
+	args := []interface{}{placeID, sign, float64(sign) * overallScore(scores)}
+	for _, score := range scoreArgs(scores) {
+		s := score.(*int)
+		if s == nil {
+			args = append(args, 0, 0)
+			continue
+		}
+		args = append(args, sign*(*s), sign)
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO review_aggregates (
+			place_id, review_count, overall_sum,
+			espresso_sum, espresso_count, milk_drinks_sum, milk_drinks_count,
+			ambiance_sum, ambiance_count, seating_sum, seating_count,
+			wifi_sum, wifi_count, value_sum, value_count, service_sum, service_count
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+		ON CONFLICT (place_id) DO UPDATE SET
+			review_count = review_aggregates.review_count + EXCLUDED.review_count,
+			overall_sum = review_aggregates.overall_sum + EXCLUDED.overall_sum,
+			espresso_sum = review_aggregates.espresso_sum + EXCLUDED.espresso_sum,
+			espresso_count = review_aggregates.espresso_count + EXCLUDED.espresso_count,
+			milk_drinks_sum = review_aggregates.milk_drinks_sum + EXCLUDED.milk_drinks_sum,
+			milk_drinks_count = review_aggregates.milk_drinks_count + EXCLUDED.milk_drinks_count,
+			ambiance_sum = review_aggregates.ambiance_sum + EXCLUDED.ambiance_sum,
+			ambiance_count = review_aggregates.ambiance_count + EXCLUDED.ambiance_count,
+			seating_sum = review_aggregates.seating_sum + EXCLUDED.seating_sum,
+			seating_count = review_aggregates.seating_count + EXCLUDED.seating_count,
+			wifi_sum = review_aggregates.wifi_sum + EXCLUDED.wifi_sum,
+			wifi_count = review_aggregates.wifi_count + EXCLUDED.wifi_count,
+			value_sum = review_aggregates.value_sum + EXCLUDED.value_sum,
+			value_count = review_aggregates.value_count + EXCLUDED.value_count,
+			service_sum = review_aggregates.service_sum + EXCLUDED.service_sum,
+			service_count = review_aggregates.service_count + EXCLUDED.service_count,
+			updated_at = NOW()
+	`, args...)
+	return err
+}
+
+// overallScore returns the mean of the metrics rated in a single review
+func overallScore(scores models.ReviewScores) float64 {
+	sum, count := 0, 0
+	for _, score := range scoreArgs(scores) {
+		if s := score.(*int); s != nil {
+			sum += *s
+			count++
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return float64(sum) / float64(count)
+}
+
+// GetReviewAggregates retrieves Ristretto ratings for a set of places in a
+// single query. Places without reviews are absent from the returned map.
+func (db *DB) GetReviewAggregates(placeIDs []string) (map[string]models.RistrettoRating, error) {
+	ratings := make(map[string]models.RistrettoRating)
+	if len(placeIDs) == 0 {
+		return ratings, nil
+	}
+
+	rows, err := db.Query(`
+		SELECT place_id, review_count, overall_sum,
+			espresso_sum, espresso_count, milk_drinks_sum, milk_drinks_count,
+			ambiance_sum, ambiance_count, seating_sum, seating_count,
+			wifi_sum, wifi_count, value_sum, value_count, service_sum, service_count
+		FROM review_aggregates
+		WHERE place_id = ANY($1) AND review_count > 0
+	`, pq.Array(placeIDs))
+	if err != nil {
+		return ratings, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			placeID     string
+			reviewCount int
+			overallSum  float64
+			metrics     [14]int
+		)
+
+		dest := []interface{}{&placeID, &reviewCount, &overallSum}
+		for i := range metrics {
+			dest = append(dest, &metrics[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return ratings, err
+		}
+
+		ratings[placeID] = models.RistrettoRating{
+			Score:       roundScore(bayesianScore(overallSum, reviewCount)),
+			Average:     roundScore(overallSum / float64(reviewCount)),
+			ReviewCount: reviewCount,
+			Metrics: models.ReviewMetricAverages{
+				Espresso:   metricAverage(metrics[0], metrics[1]),
+				MilkDrinks: metricAverage(metrics[2], metrics[3]),
+				Ambiance:   metricAverage(metrics[4], metrics[5]),
+				Seating:    metricAverage(metrics[6], metrics[7]),
+				Wifi:       metricAverage(metrics[8], metrics[9]),
+				Value:      metricAverage(metrics[10], metrics[11]),
+				Service:    metricAverage(metrics[12], metrics[13]),
+			},
+		}
+	}
+
+	return ratings, rows.Err()
+}
+
+// GetReviewAggregate retrieves the Ristretto rating for a single place
+func (db *DB) GetReviewAggregate(placeID string) (*models.RistrettoRating, error) {
+	ratings, err := db.GetReviewAggregates([]string{placeID})
+	if err != nil {
+		return nil, err
+	}
+	rating, ok := ratings[placeID]
+	if !ok {
+		return nil, nil
+	}
+	return &rating, nil
+}
+
+// bayesianScore weights a place's overall average against the prior mean
+func bayesianScore(overallSum float64, reviewCount int) float64 {
+	return (bayesianPriorWeight*bayesianPriorMean + overallSum) / (bayesianPriorWeight + float64(reviewCount))
+}
+
+// metricAverage returns the average of a metric, or nil if it was never rated
+func metricAverage(sum, count int) *float64 {
+	if count <= 0 {
+		return nil
+	}
+	avg := roundScore(float64(sum) / float64(count))
+	return &avg
+}
+
+// roundScore rounds a score to two decimal places
+func roundScore(score float64) float64 {
+	return math.Round(score*100) / 100
+}
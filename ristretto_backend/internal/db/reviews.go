@@ -0,0 +1,339 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
+)
+
+// ErrDuplicateReview is returned when a user tries to review the same coffee shop twice
+var ErrDuplicateReview = errors.New("user has already reviewed this coffee shop")
+
+// reviewColumns is the column list shared by all review queries. The reviews
+// and review_aggregates tables are created by migration 0002_reviews.
+const reviewColumns = `
+	r.id, r.place_id, r.user_id,
+	COALESCE(u.first_name, ''), COALESCE(u.last_name, ''),
+	r.espresso, r.milk_drinks, r.ambiance, r.seating, r.wifi, r.value, r.service,
+	r.body, r.created_at, r.updated_at`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanReview scans a single review row selected with reviewColumns
+func scanReview(row rowScanner) (models.Review, error) {
+	var (
+		review    models.Review
+		userID    sql.NullInt64
+		firstName string
+		lastName  string
+		scores    [7]sql.NullInt64
+	)
+
+	err := row.Scan(
+		&review.ID, &review.PlaceID, &userID,
+		&firstName, &lastName,
+		&scores[0], &scores[1], &scores[2], &scores[3], &scores[4], &scores[5], &scores[6],
+		&review.Body, &review.CreatedAt, &review.UpdatedAt,
+	)
+	if err != nil {
+		return review, err
+	}
+
+	// Reviews by deleted users are kept without an author
+	review.UserID = int(userID.Int64)
+	review.Author = authorName(firstName, lastName)
+	review.Scores = scoresFromNullable(scores)
+
+	return review, nil
+}
+
+// scoresFromNullable converts nullable metric columns, in scoreArgs order, into review scores
+func scoresFromNullable(scores [7]sql.NullInt64) models.ReviewScores {
+	return models.ReviewScores{
+		Espresso:   nullableScore(scores[0]),
+		MilkDrinks: nullableScore(scores[1]),
+		Ambiance:   nullableScore(scores[2]),
+		Seating:    nullableScore(scores[3]),
+		Wifi:       nullableScore(scores[4]),
+		Value:      nullableScore(scores[5]),
+		Service:    nullableScore(scores[6]),
+	}
+}
+
+// nullableScore converts a nullable database score into an optional int
+func nullableScore(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	score := int(n.Int64)
+	return &score
+}
+
+// authorName builds the public display name of a review author, e.g. "Jane D."
+func authorName(firstName, lastName string) string {
+	if firstName == "" && lastName == "" {
+		return "Anonymous"
+	}
+	if lastName == "" {
+		return firstName
+	}
+	return firstName + " " + string([]rune(lastName)[:1]) + "."
+}
+
+// scoreArgs returns the scores in column order as query arguments
+func scoreArgs(s models.ReviewScores) []interface{} {
+	return []interface{}{s.Espresso, s.MilkDrinks, s.Ambiance, s.Seating, s.Wifi, s.Value, s.Service}
+}
+
+// GetReviews retrieves all visible reviews for a coffee shop, newest first
+func (db *DB) GetReviews(placeID string) ([]models.Review, error) {
+	reviews := []models.Review{}
+
+	rows, err := db.Query(`
+		SELECT `+reviewColumns+`
+		FROM reviews r
+		LEFT JOIN users u ON u.id = r.user_id
+		WHERE r.place_id = $1 AND r.hidden_at IS NULL
+		ORDER BY r.created_at DESC
+	`, placeID)
+	if err != nil {
+		return reviews, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return reviews, err
+		}
+		reviews = append(reviews, review)
+	}
+
+	return reviews, rows.Err()
+}
+
+// GetReview retrieves a single review by ID, including hidden reviews
+func (db *DB) GetReview(reviewID int) (models.Review, error) {
+	return scanReview(db.QueryRow(`
+		SELECT `+reviewColumns+`
+		FROM reviews r
+		LEFT JOIN users u ON u.id = r.user_id
+		WHERE r.id = $1
+	`, reviewID))
+}
+
+// CreateReview creates a review for a coffee shop and returns its ID. The
+// place's review aggregate is updated in the same transaction.
+func (db *DB) CreateReview(userID int, placeID string, req models.ReviewRequest) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := ensureCoffeeShop(tx, placeID, "", nil, nil); err != nil {
+		return 0, err
+	}
+
+	args := append([]interface{}{userID, placeID}, scoreArgs(req.Scores)...)
+	args = append(args, req.Body)
+
+	var reviewID int
+	err = tx.QueryRow(`
+		INSERT INTO reviews (user_id, place_id, espresso, milk_drinks, ambiance, seating, wifi, value, service, body)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id
+	`, args...).Scan(&reviewID)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return 0, ErrDuplicateReview
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if err := applyReviewDelta(tx, placeID, req.Scores, 1); err != nil {
+		return 0, err
+	}
+
+	return reviewID, tx.Commit()
+}
+
+// UpdateReview updates a review owned by the given user and adjusts the
+// place's review aggregate in the same transaction
+func (db *DB) UpdateReview(reviewID, userID int, req models.ReviewRequest) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	locked, err := lockReview(tx, "id = $1 AND user_id = $2", reviewID, userID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	args := append([]interface{}{reviewID, userID}, scoreArgs(req.Scores)...)
+	args = append(args, req.Body)
+
+	result, err := tx.Exec(`
+		UPDATE reviews
+		SET espresso = $3, milk_drinks = $4, ambiance = $5, seating = $6,
+			wifi = $7, value = $8, service = $9, body = $10, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+	`, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	// Hidden reviews are already excluded from the aggregate
+	if !locked.hidden {
+		if err := applyReviewDelta(tx, locked.placeID, locked.scores, -1); err != nil {
+			return 0, err
+		}
+		if err := applyReviewDelta(tx, locked.placeID, req.Scores, 1); err != nil {
+			return 0, err
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return rowsAffected, tx.Commit()
+}
+
+// DeleteReview deletes a review owned by the given user and removes it from
+// the place's review aggregate in the same transaction
+func (db *DB) DeleteReview(reviewID, userID int) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	locked, err := lockReview(tx, "id = $1 AND user_id = $2", reviewID, userID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(`
+		DELETE FROM reviews
+		WHERE id = $1 AND user_id = $2
+	`, reviewID, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if !locked.hidden {
+		if err := applyReviewDelta(tx, locked.placeID, locked.scores, -1); err != nil {
+			return 0, err
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return rowsAffected, tx.Commit()
+}
+
+// SetReviewHidden hides or unhides a review on behalf of a moderator and
+// removes it from or restores it to the place's review aggregate
+func (db *DB) SetReviewHidden(reviewID, moderatorID int, hidden bool) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	locked, err := lockReview(tx, "id = $1", reviewID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// Nothing to do if the review is already in the requested state
+	if locked.hidden == hidden {
+		return 1, tx.Commit()
+	}
+
+	if hidden {
+		_, err = tx.Exec(`
+			UPDATE reviews
+			SET hidden_at = NOW(), hidden_by = $2
+			WHERE id = $1
+		`, reviewID, moderatorID)
+	} else {
+		_, err = tx.Exec(`
+			UPDATE reviews
+			SET hidden_at = NULL, hidden_by = NULL
+			WHERE id = $1
+		`, reviewID)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	sign := 1
+	if hidden {
+		sign = -1
+	}
+	if err := applyReviewDelta(tx, locked.placeID, locked.scores, sign); err != nil {
+		return 0, err
+	}
+
+	return 1, tx.Commit()
+}
+
+// lockedReview is the state of a review read under a row lock
+type lockedReview struct {
+	placeID string
+	scores  models.ReviewScores
+	hidden  bool
+}
+
+// lockReview locks the review matching condition and returns its place ID,
+// current scores and whether it is hidden
+func lockReview(tx *sql.Tx, condition string, args ...interface{}) (lockedReview, error) {
+	var (
+		locked   lockedReview
+		scores   [7]sql.NullInt64
+		hiddenAt sql.NullTime
+	)
+
+	err := tx.QueryRow(`
+		SELECT place_id, espresso, milk_drinks, ambiance, seating, wifi, value, service, hidden_at
+		FROM reviews
+		WHERE `+condition+`
+		FOR UPDATE
+	`, args...).Scan(
+		&locked.placeID,
+		&scores[0], &scores[1], &scores[2], &scores[3], &scores[4], &scores[5], &scores[6],
+		&hiddenAt,
+	)
+	if err != nil {
+		return locked, err
+	}
+
+	locked.scores = scoresFromNullable(scores)
+	locked.hidden = hiddenAt.Valid
+	return locked, nil
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
+)
+
+// columnName converts a review metric name such as milkDrinks to its column name
+func columnName(metric string) string {
+	var b strings.Builder
+	for _, r := range metric {
+		if unicode.IsUpper(r) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// TestReviewSchemaCoversMetrics checks that the migrations create a column
+// for every review metric in both review tables, so the review queries and
+// the schema can't drift apart
+func TestReviewSchemaCoversMetrics(t *testing.T) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+
+	// Collapse whitespace so column alignment doesn't matter
+	var scripts []string
+	for _, m := range migrations {
+		scripts = append(scripts, m.Up)
+	}
+	schema := strings.Join(strings.Fields(strings.Join(scripts, "\n")), " ")
+
+	for _, table := range []string{"CREATE TABLE reviews (", "CREATE TABLE review_aggregates ("} {
+		if !strings.Contains(schema, table) {
+			t.Fatalf("migrations never run %q", table)
+		}
+	}
+
+	for _, metric := range (models.ReviewScores{}).Metrics() {
+		column := columnName(metric.Name)
+		for _, want := range []string{
+			column + " SMALLINT CHECK (" + column + " BETWEEN 1 AND 5)",
+			column + "_sum ",
+			column + "_count ",
+		} {
+			if !strings.Contains(schema, want) {
+				t.Errorf("metric %s: migrations have no %q", metric.Name, want)
+			}
+		}
+		if !strings.Contains(reviewColumns, "r."+column) {
+			t.Errorf("metric %s: review queries don't select r.%s", metric.Name, column)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package httpclient
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling the upstream while the circuit
+// breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open: upstream unhealthy")
+
+// ErrNotSent marks errors from transports that refused a request without
+// sending it upstream, such as a spent call budget. They say nothing about
+// the upstream, so they are not retried or counted by the breaker.
+var ErrNotSent = errors.New("request not sent")
+
+// Circuit breaker states
+const (
+	stateClosed   = "closed"    // Calls go through
+	stateOpen     = "open"      // Calls fail fast until the cooldown ends
+	stateHalfOpen = "half-open" // One trial call decides whether to close again
+)
+
+// Breaker opens after a run of consecutive failures and fails calls fast
+// until a cooldown has passed. A single trial call then closes it on success
+// or reopens it on failure.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+	logger    *slog.Logger
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	trial    bool // A half-open trial call is in flight
+	now      func() time.Time
+}
+
+// NewBreaker creates a breaker that opens after threshold consecutive
+// failures. A threshold of zero or less disables it.
+func NewBreaker(threshold int, cooldown time.Duration, logger *slog.Logger) *Breaker {
+	return &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		logger:    logger,
+		state:     stateClosed,
+		now:       time.Now,
+	}
+}
+
+// allow reports whether a call may go through
+func (b *Breaker) allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(stateHalfOpen)
+		b.trial = true
+		return true
+	case stateHalfOpen:
+		// Only the trial call goes through until it finishes
+		if b.trial {
+			return false
+		}
+		b.trial = true
+		return true
+	default:
+		return true
+	}
+}
+
+// record updates the breaker with the result of a call
+func (b *Breaker) record(success bool) {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == stateHalfOpen {
+		b.trial = false
+	}
+	if success {
+		b.failures = 0
+		b.setState(stateClosed)
+		return
+	}
+
+	b.failures++
+	if b.state == stateHalfOpen || b.failures >= b.threshold {
+		b.openedAt = b.now()
+		b.setState(stateOpen)
+	}
+}
+
+// setState moves to state, logging transitions. Callers must hold b.mu.
+func (b *Breaker) setState(state string) {
+	if b.state == state {
+		return
+	}
+	b.logger.Warn("Circuit breaker changed state", "from", b.state, "to", state, "failures", b.failures)
+	b.state = state
+}
+
+// breakerTransport guards an upstream with a Breaker
+type breakerTransport struct {
+	next    http.RoundTripper
+	breaker *Breaker
+}
+
+// NewBreakerTransport wraps next so that calls fail fast with ErrCircuitOpen
+// while breaker is open. Network errors, 429s and 5xx responses count as
+// failures; other responses mean the upstream is healthy.
+func NewBreakerTransport(next http.RoundTripper, breaker *Breaker) http.RoundTripper {
+	return &breakerTransport{next: next, breaker: breaker}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.breaker.allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil && (req.Context().Err() != nil || errors.Is(err, ErrNotSent)) {
+		// The caller gave up or the request was never sent, which says
+		// nothing about the upstream. Release a half-open trial without
+		// changing state.
+		t.breaker.release()
+		return resp, err
+	}
+	t.breaker.record(err == nil && resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < http.StatusInternalServerError)
+	return resp, err
+}
+
+// release ends a half-open trial call without a verdict
+func (b *Breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
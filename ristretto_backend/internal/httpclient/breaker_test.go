@@ -0,0 +1,124 @@
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// breakerServer is an upstream whose health can be toggled
+type breakerServer struct {
+	*httptest.Server
+	calls   atomic.Int32
+	healthy atomic.Bool
+}
+
+// newBreakerServer starts an unhealthy upstream
+func newBreakerServer(t *testing.T) *breakerServer {
+	t.Helper()
+	s := &breakerServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.calls.Add(1)
+		if !s.healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// newTestBreaker creates a breaker with a manually advanced clock
+func newTestBreaker(threshold int, cooldown time.Duration) (*Breaker, *time.Time) {
+	now := time.Now()
+	breaker := NewBreaker(threshold, cooldown, discardLogger)
+	breaker.now = func() time.Time { return now }
+	return breaker, &now
+}
+
+// get sends a GET through client and returns the status, or the error
+func get(t *testing.T, client *http.Client, url string) (int, error) {
+	t.Helper()
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func TestBreakerOpensAndRecovers(t *testing.T) {
+	server := newBreakerServer(t)
+	breaker, now := newTestBreaker(3, time.Minute)
+	client := &http.Client{Transport: NewBreakerTransport(http.DefaultTransport, breaker)}
+
+	for i := 0; i < 3; i++ {
+		if status, err := get(t, client, server.URL); err != nil || status != http.StatusInternalServerError {
+			t.Fatalf("call %d = %d, %v; want 500", i, status, err)
+		}
+	}
+
+	// Open: calls fail fast without reaching the upstream
+	if _, err := get(t, client, server.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+	if server.calls.Load() != 3 {
+		t.Fatalf("upstream calls = %d, want 3", server.calls.Load())
+	}
+
+	// Half-open: a failed trial call reopens the breaker
+	*now = now.Add(time.Minute + time.Second)
+	if status, _ := get(t, client, server.URL); status != http.StatusInternalServerError {
+		t.Fatalf("trial status = %d, want 500", status)
+	}
+	if _, err := get(t, client, server.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err after failed trial = %v, want ErrCircuitOpen", err)
+	}
+
+	// Half-open: a successful trial call closes it
+	server.healthy.Store(true)
+	*now = now.Add(time.Minute + time.Second)
+	for i := 0; i < 3; i++ {
+		if status, err := get(t, client, server.URL); err != nil || status != http.StatusOK {
+			t.Fatalf("call %d after recovery = %d, %v; want 200", i, status, err)
+		}
+	}
+}
+
+func TestBreakerAllowsOneTrialCall(t *testing.T) {
+	breaker, now := newTestBreaker(1, time.Minute)
+	breaker.record(false)
+
+	*now = now.Add(time.Minute + time.Second)
+	if !breaker.allow() {
+		t.Fatal("trial call refused")
+	}
+	if breaker.allow() {
+		t.Fatal("second call allowed while the trial is in flight")
+	}
+
+	breaker.record(true)
+	if !breaker.allow() {
+		t.Fatal("call refused after a successful trial")
+	}
+}
+
+func TestRetryStopsWhenBreakerOpens(t *testing.T) {
+	server := newBreakerServer(t)
+	breaker, _ := newTestBreaker(2, time.Minute)
+	transport := NewBreakerTransport(http.DefaultTransport, breaker)
+	client := &http.Client{Transport: NewRetryTransport(transport, RetryPolicy{
+		MaxRetries: 5,
+		BaseDelay:  time.Millisecond,
+		MaxDelay:   time.Millisecond,
+	}, discardLogger)}
+
+	if _, err := get(t, client, server.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+	if server.calls.Load() != 2 {
+		t.Errorf("upstream calls = %d, want 2", server.calls.Load())
+	}
+}
@@ -0,0 +1,137 @@
+// Package httpclient provides HTTP transports that make outbound calls
+// resilient: retries with jittered exponential backoff and a circuit breaker
+// that fails fast while an upstream is unhealthy
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxDrainBytes bounds how much of a retried response is read so the
+// connection can be reused
+const maxDrainBytes = 4 << 10
+
+// RetryPolicy configures retries of failed requests
+type RetryPolicy struct {
+	MaxRetries int           // Retries after the first attempt; zero disables retries
+	BaseDelay  time.Duration // Backoff before the first retry, doubled for each one after
+	MaxDelay   time.Duration // Upper bound on any single wait, including Retry-After
+}
+
+// retryTransport retries requests that failed in a way worth retrying
+type retryTransport struct {
+	next   http.RoundTripper
+	policy RetryPolicy
+	logger *slog.Logger
+}
+
+// NewRetryTransport wraps next so that network errors, 429s and 5xx
+// responses are retried. Waits honor Retry-After and otherwise use full
+// jitter exponential backoff. Requests with a body are only retried if it
+// can be replayed, and no wait outlasts the request context.
+func NewRetryTransport(next http.RoundTripper, policy RetryPolicy, logger *slog.Logger) http.RoundTripper {
+	return &retryTransport{next: next, policy: policy, logger: logger}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if attempt >= t.policy.MaxRetries || !retryable(ctx, resp, err) {
+			return resp, err
+		}
+
+		wait := t.backoff(attempt, resp)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			// Waiting would outlast the caller, so report this failure now
+			return resp, err
+		}
+
+		// Replay the body for the next attempt
+		if req.Body != nil {
+			if req.GetBody == nil {
+				return resp, err
+			}
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+
+		t.logger.WarnContext(ctx, "Retrying upstream request",
+			"host", req.URL.Host, "attempt", attempt+1, "wait", wait, "status", statusCode(resp), "err", err)
+		if resp != nil {
+			io.CopyN(io.Discard, resp.Body, maxDrainBytes)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns how long to wait before retry number attempt+1
+func (t *retryTransport) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if wait, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			return min(wait, t.policy.MaxDelay)
+		}
+	}
+
+	ceiling := min(t.policy.BaseDelay<<attempt, t.policy.MaxDelay)
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// retryable reports whether a failed attempt is worth repeating
+func retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		// The caller gave up, or the request was refused before it was sent
+		return ctx.Err() == nil && !errors.Is(err, ErrCircuitOpen) && !errors.Is(err, ErrNotSent)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+// statusCode returns the response status, or 0 if there was no response
+func statusCode(resp *http.Response) int {
+	if resp == nil {
+		return 0
+	}
+	return resp.StatusCode
+}
@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+)
+
+// UnmatchedRoute labels requests that matched no registered route, so that
+// arbitrary paths can't create unbounded label values
+const UnmatchedRoute = "unmatched"
+
+// HTTPMetrics records served HTTP requests
+type HTTPMetrics struct {
+	duration *HistogramVec
+}
+
+// NewHTTPMetrics creates and registers the HTTP request metrics
+func NewHTTPMetrics(r *Registry) *HTTPMetrics {
+	return &HTTPMetrics{
+		duration: r.NewHistogramVec("http_request_duration_seconds",
+			"Time taken to serve HTTP requests, by route pattern and status.",
+			DefaultBuckets, "method", "route", "status"),
+	}
+}
+
+// Observe records one request
+func (m *HTTPMetrics) Observe(method, route string, status int, elapsed time.Duration) {
+	m.duration.Observe(elapsed.Seconds(), method, route, strconv.Itoa(status))
+}
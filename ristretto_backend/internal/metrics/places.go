@@ -0,0 +1,152 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Places API operations, set on the request context by the places service
+const (
+	OpSearchNearby    = "SearchNearby"
+	OpGetPlaceDetails = "GetPlaceDetails"
+	OpSearchText      = "SearchText"
+	OpAutocomplete    = "Autocomplete"
+	OpPhoto           = "photo"
+	OpPing            = "Ping" // Readiness checks, which are not billed
+	opUnknown         = "unknown"
+)
+
+// Outcomes of a Places API call
+const (
+	OutcomeSuccess     = "success"
+	OutcomeClientError = "client_error"
+	OutcomeServerError = "server_error"
+	OutcomeTimeout     = "timeout"
+	OutcomeError       = "error" // The request never got a response
+)
+
+// PlacesMetrics records calls to the Google Places API, which are billed per call
+type PlacesMetrics struct {
+	calls            *CounterVec
+	duration         *HistogramVec
+	budgetRejections *CounterVec
+}
+
+// NewPlacesMetrics creates and registers the Places API metrics
+func NewPlacesMetrics(r *Registry) *PlacesMetrics {
+	return &PlacesMetrics{
+		calls: r.NewCounterVec("places_api_calls_total",
+			"Calls made to the Google Places API, by operation and outcome.",
+			"operation", "outcome"),
+		duration: r.NewHistogramVec("places_api_call_duration_seconds",
+			"Latency of Google Places API calls, by operation and outcome.",
+			DefaultBuckets, "operation", "outcome"),
+		budgetRejections: r.NewCounterVec("places_api_budget_rejections_total",
+			"Google Places API calls refused because the daily call budget was spent.",
+			"operation"),
+	}
+}
+
+// ObserveCall records one Places API call
+func (m *PlacesMetrics) ObserveCall(operation, outcome string, elapsed time.Duration) {
+	m.calls.Inc(operation, outcome)
+	m.duration.Observe(elapsed.Seconds(), operation, outcome)
+}
+
+// BudgetRejected records a call refused by the daily budget, labeled with
+// the operation set on ctx
+func (m *PlacesMetrics) BudgetRejected(ctx context.Context) {
+	if m == nil {
+		return
+	}
+	m.budgetRejections.Inc(operationFromContext(ctx))
+}
+
+// Results of a places cache lookup
+const (
+	CacheHit    = "hit"
+	CacheMiss   = "miss"
+	CacheBypass = "bypass" // The caller asked to skip the cache
+	CacheStale  = "stale"  // After a miss, an expired entry was served because the upstream call was refused
+)
+
+// PlacesCacheMetrics records lookups in the places cache
+type PlacesCacheMetrics struct {
+	requests *CounterVec
+}
+
+// NewPlacesCacheMetrics creates and registers the places cache metrics
+func NewPlacesCacheMetrics(r *Registry) *PlacesCacheMetrics {
+	return &PlacesCacheMetrics{
+		requests: r.NewCounterVec("places_cache_requests_total",
+			"Places cache lookups, by call type and result.",
+			"call", "result"),
+	}
+}
+
+// ObserveLookup records one cache lookup
+func (m *PlacesCacheMetrics) ObserveLookup(call, result string) {
+	if m == nil {
+		return
+	}
+	m.requests.Inc(call, result)
+}
+
+// operationKey is the context key for the Places API operation
+type operationKey struct{}
+
+// WithOperation labels the Places API calls made with ctx
+func WithOperation(ctx context.Context, operation string) context.Context {
+	return context.WithValue(ctx, operationKey{}, operation)
+}
+
+// operationFromContext returns the operation set by WithOperation
+func operationFromContext(ctx context.Context) string {
+	if op, ok := ctx.Value(operationKey{}).(string); ok {
+		return op
+	}
+	return opUnknown
+}
+
+// placesTransport records every round trip it makes
+type placesTransport struct {
+	next    http.RoundTripper
+	metrics *PlacesMetrics
+}
+
+// InstrumentTransport wraps next so that every request is recorded in m,
+// labeled with the operation set on its context by WithOperation
+func InstrumentTransport(next http.RoundTripper, m *PlacesMetrics) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &placesTransport{next: next, metrics: m}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *placesTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	t.metrics.ObserveCall(operationFromContext(req.Context()), outcome(resp, err), time.Since(start))
+	return resp, err
+}
+
+// outcome classifies the result of a round trip
+func outcome(resp *http.Response, err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return OutcomeTimeout
+	case err != nil:
+		return OutcomeError
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return OutcomeServerError
+	case resp.StatusCode >= http.StatusBadRequest:
+		return OutcomeClientError
+	default:
+		return OutcomeSuccess
+	}
+}
@@ -0,0 +1,16 @@
+package models
+
+// UsersResponse represents the response for the admin user list
+type UsersResponse struct {
+	Users []User `json:"users"`
+}
+
+// RoleRequest represents the request body for changing a user's role
+type RoleRequest struct {
+	Role string `json:"role"`
+}
+
+// ReviewVisibilityRequest represents the request body for hiding or unhiding a review
+type ReviewVisibilityRequest struct {
+	Hidden bool `json:"hidden"`
+}
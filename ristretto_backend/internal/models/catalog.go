@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// CatalogCoffeeShop is a coffee shop stored in our local catalog, keyed by
+// Google place ID. Favorites, visits and reviews all reference it.
+type CatalogCoffeeShop struct {
+	PlaceID       string        `json:"placeId"`
+	Name          string        `json:"name"`
+	Latitude      float64       `json:"latitude"`
+	Longitude     float64       `json:"longitude"`
+	Address       string        `json:"address,omitempty"`
+	PhoneNumber   string        `json:"phoneNumber,omitempty"`
+	Website       string        `json:"website,omitempty"`
+	GoogleMapsURI string        `json:"googleMapsUri,omitempty"`
+	Rating        float64       `json:"rating,omitempty"`
+	PriceLevel    int           `json:"priceLevel,omitempty"`
+	OpeningHours  *OpeningHours `json:"openingHours,omitempty"`
+	Photos        []*Photo      `json:"photos,omitempty"`
+	LastSyncedAt  *time.Time    `json:"lastSyncedAt,omitempty"` // Nil until synced from Places
+	NotFoundAt    *time.Time    `json:"notFoundAt,omitempty"`   // Set once Places reports the place no longer exists
+}
+
+// NewCatalogCoffeeShop builds a catalog entry from Google Places details
+func NewCatalogCoffeeShop(details *PlaceDetails) CatalogCoffeeShop {
+	return CatalogCoffeeShop{
+		PlaceID:       details.PlaceID,
+		Name:          details.DisplayName.Text,
+		Latitude:      details.Location.Latitude,
+		Longitude:     details.Location.Longitude,
+		Address:       details.FormattedAddress,
+		PhoneNumber:   details.InternationalPhoneNumber,
+		Website:       details.WebsiteURI,
+		GoogleMapsURI: details.GoogleMapsURI,
+		Rating:        details.Rating,
+		PriceLevel:    int(details.PriceLevel),
+		OpeningHours:  details.CurrentOpeningHours,
+		Photos:        details.Photos,
+	}
+}
+
+// PlaceDetails converts a catalog entry back into the Google Places details shape
+func (c CatalogCoffeeShop) PlaceDetails() *PlaceDetails {
+	return &PlaceDetails{
+		PlaceID:                  c.PlaceID,
+		DisplayName:              DisplayName{Text: c.Name},
+		FormattedAddress:         c.Address,
+		Location:                 Location{Latitude: c.Latitude, Longitude: c.Longitude},
+		GoogleMapsURI:            c.GoogleMapsURI,
+		WebsiteURI:               c.Website,
+		InternationalPhoneNumber: c.PhoneNumber,
+		Rating:                   c.Rating,
+		PriceLevel:               PriceLevel(c.PriceLevel),
+		CurrentOpeningHours:      c.OpeningHours,
+		Photos:                   c.Photos,
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+import "encoding/json"
+
+// Clerk webhook event types handled by the server
+const (
+	ClerkEventUserCreated = "user.created"
+	ClerkEventUserUpdated = "user.updated"
+	ClerkEventUserDeleted = "user.deleted"
+)
+
+// ClerkWebhookEvent is the envelope of a Clerk webhook
+type ClerkWebhookEvent struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// ClerkUser is the user object sent with user.created and user.updated
+// events. user.deleted events only carry the ID.
+type ClerkUser struct {
+	ID                    string              `json:"id"`
+	FirstName             *string             `json:"first_name"`
+	LastName              *string             `json:"last_name"`
+	PrimaryEmailAddressID *string             `json:"primary_email_address_id"`
+	EmailAddresses        []ClerkEmailAddress `json:"email_addresses"`
+}
+
+// ClerkEmailAddress is one of a Clerk user's email addresses
+type ClerkEmailAddress struct {
+	ID           string `json:"id"`
+	EmailAddress string `json:"email_address"`
+}
+
+// PrimaryEmail returns the user's primary email address, or the first one if
+// no primary is set
+func (u ClerkUser) PrimaryEmail() string {
+	for _, email := range u.EmailAddresses {
+		if u.PrimaryEmailAddressID != nil && email.ID == *u.PrimaryEmailAddressID {
+			return email.EmailAddress
+		}
+	}
+	if len(u.EmailAddresses) > 0 {
+		return u.EmailAddresses[0].EmailAddress
+	}
+	return ""
+}
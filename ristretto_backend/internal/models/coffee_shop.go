@@ -7,11 +7,31 @@ type CoffeeShop struct {
 	Latitude   float64 `json:"latitude"`
 	Longitude  float64 `json:"longitude"`
 	IsFavorite bool    `json:"isFavorite,omitempty"`
+
+	Distance       float64  `json:"distance,omitempty"` // Meters from the query point
+	Rating         float64  `json:"rating,omitempty"`   // Google rating
+	PriceLevel     int      `json:"priceLevel,omitempty"`
+	OpenNow        *bool    `json:"openNow,omitempty"`
+	IsVisited      bool     `json:"isVisited,omitempty"`
+	RistrettoScore *float64 `json:"ristrettoScore,omitempty"`
+	ReviewCount    int      `json:"reviewCount,omitempty"`
 }
 
+// Data sources reported in the "source" field of responses not served live from the places provider
+const (
+	DataSourceMock    = "mock"    // Mock data instead of real place data
+	DataSourceCatalog = "catalog" // Last synced copy from our local catalog
+)
+
 // CoffeeShopsResponse represents the response for the coffee shops endpoint
 type CoffeeShopsResponse struct {
 	CoffeeShops []CoffeeShop `json:"coffeeShops"`
+	NextCursor  string       `json:"nextCursor,omitempty"` // Pass as ?cursor= to fetch the next page; only set when sorted by distance
+	// SortedWithin is set for sorts other than distance to how many of the
+	// nearest matching shops the sort was applied across; shops further away
+	// were not considered
+	SortedWithin int    `json:"sortedWithin,omitempty"`
+	Source       string `json:"source,omitempty"` // Set to DataSourceMock for mock data
 }
 
 // FavoriteCoffeeShop represents a favorite coffee shop stored in the database
@@ -30,3 +50,8 @@ type Visit struct {
 	Name      string `json:"name"`
 	VisitedAt string `json:"visitedAt"`
 }
+
+// CoffeeShopSuggestionsResponse represents the response for the autocomplete endpoint
+type CoffeeShopSuggestionsResponse struct {
+	Suggestions []PlaceSuggestion `json:"suggestions"`
+}
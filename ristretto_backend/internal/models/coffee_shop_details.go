@@ -13,12 +13,25 @@ type CoffeeShopDetails struct {
 	IsFavorite   bool     `json:"isFavorite"`
 	PriceLevel   int      `json:"priceLevel,omitempty"`
 	OpeningHours []string `json:"openingHours,omitempty"`
-	Photos       []string `json:"photos,omitempty"`
+
+	Photos []CoffeeShopPhoto `json:"photos,omitempty"`
+
+	Ristretto *RistrettoRating `json:"ristretto,omitempty"`
+}
+
+// CoffeeShopPhoto is a photo of a coffee shop served through our photo proxy.
+// Google requires its author attributions to be shown alongside it.
+type CoffeeShopPhoto struct {
+	URL          string              `json:"url"`
+	Width        int                 `json:"width,omitempty"` // Pixel size of the served image, if known
+	Height       int                 `json:"height,omitempty"`
+	Attributions []AuthorAttribution `json:"attributions,omitempty"`
 }
 
 // CoffeeShopDetailsResponse represents the response for the coffee shop details endpoint
 type CoffeeShopDetailsResponse struct {
 	CoffeeShop CoffeeShopDetails `json:"coffeeShop"`
+	Source     string            `json:"source,omitempty"` // DataSourceMock or DataSourceCatalog when not live
 }
 
 // PlaceDetails represents the response from the Google Places API for a place details request
@@ -31,7 +44,7 @@ type PlaceDetails struct {
 	WebsiteURI               string        `json:"websiteUri,omitempty"`
 	InternationalPhoneNumber string        `json:"internationalPhoneNumber,omitempty"`
 	Rating                   float64       `json:"rating,omitempty"`
-	PriceLevel               int           `json:"priceLevel,omitempty"`
+	PriceLevel               PriceLevel    `json:"priceLevel,omitempty"`
 	CurrentOpeningHours      *OpeningHours `json:"currentOpeningHours,omitempty"`
 	Photos                   []*Photo      `json:"photos,omitempty"`
 }
@@ -67,6 +80,15 @@ type TimeOfDay struct {
 
 // Photo represents a photo from the Google Places API
 type Photo struct {
-	Name        string `json:"name"`
-	Attribution string `json:"authorAttribution,omitempty"`
+	Name               string              `json:"name"`
+	WidthPx            int                 `json:"widthPx,omitempty"` // Maximum available size
+	HeightPx           int                 `json:"heightPx,omitempty"`
+	AuthorAttributions []AuthorAttribution `json:"authorAttributions,omitempty"`
+}
+
+// AuthorAttribution credits the author of a photo
+type AuthorAttribution struct {
+	DisplayName string `json:"displayName"`
+	URI         string `json:"uri,omitempty"`      // Author's profile
+	PhotoURI    string `json:"photoUri,omitempty"` // Author's profile photo
 }
@@ -40,4 +40,61 @@ type Place struct {
 		Latitude  float64 `json:"latitude"`
 		Longitude float64 `json:"longitude"`
 	} `json:"location"`
+	Rating              float64     `json:"rating,omitempty"`
+	PriceLevel          PriceLevel  `json:"priceLevel,omitempty"`
+	CurrentOpeningHours *OpenStatus `json:"currentOpeningHours,omitempty"`
+}
+
+// OpenStatus holds whether a place is currently open
+type OpenStatus struct {
+	OpenNow bool `json:"openNow"`
+}
+
+// LocationBias prefers, but does not restrict to, results inside an area
+type LocationBias struct {
+	Circle Circle `json:"circle"`
+}
+
+// TextSearchRequest represents a text search request to the Google Places API
+type TextSearchRequest struct {
+	TextQuery           string        `json:"textQuery"`
+	IncludedType        string        `json:"includedType,omitempty"`
+	StrictTypeFiltering bool          `json:"strictTypeFiltering,omitempty"`
+	PageSize            int           `json:"pageSize,omitempty"`
+	LocationBias        *LocationBias `json:"locationBias,omitempty"`
+}
+
+// AutocompleteRequest represents an autocomplete request to the Google Places API
+type AutocompleteRequest struct {
+	Input                string        `json:"input"`
+	IncludedPrimaryTypes []string      `json:"includedPrimaryTypes,omitempty"`
+	LocationBias         *LocationBias `json:"locationBias,omitempty"`
+	SessionToken         string        `json:"sessionToken,omitempty"`
+}
+
+// AutocompleteResponse represents an autocomplete response from the Google Places API
+type AutocompleteResponse struct {
+	Suggestions []struct {
+		PlacePrediction *PlacePrediction `json:"placePrediction,omitempty"`
+	} `json:"suggestions"`
+}
+
+// PlacePrediction is a single place suggestion from the Google Places API
+type PlacePrediction struct {
+	PlaceID          string `json:"placeId"`
+	StructuredFormat struct {
+		MainText struct {
+			Text string `json:"text"`
+		} `json:"mainText"`
+		SecondaryText struct {
+			Text string `json:"text"`
+		} `json:"secondaryText"`
+	} `json:"structuredFormat"`
+}
+
+// PlaceSuggestion is a provider-independent autocomplete suggestion
+type PlaceSuggestion struct {
+	PlaceID       string `json:"id"`
+	Name          string `json:"name"`
+	SecondaryText string `json:"secondaryText,omitempty"`
 }
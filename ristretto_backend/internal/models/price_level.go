@@ -0,0 +1,41 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// PriceLevel is a coffee shop's price level from 0 (free) to 4 (very expensive).
+// The Google Places API (New) returns it as an enum string such as
+// "PRICE_LEVEL_MODERATE"; fixtures and our own storage use the number.
+type PriceLevel int
+
+// priceLevelsByName maps Google Places price level enum values to numbers
+var priceLevelsByName = map[string]PriceLevel{
+	"PRICE_LEVEL_UNSPECIFIED":    0,
+	"PRICE_LEVEL_FREE":           0,
+	"PRICE_LEVEL_INEXPENSIVE":    1,
+	"PRICE_LEVEL_MODERATE":       2,
+	"PRICE_LEVEL_EXPENSIVE":      3,
+	"PRICE_LEVEL_VERY_EXPENSIVE": 4,
+}
+
+// UnmarshalJSON accepts either a Google enum string or a number
+func (p *PriceLevel) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		level, ok := priceLevelsByName[name]
+		if !ok {
+			return fmt.Errorf("unknown price level: %s", name)
+		}
+		*p = level
+		return nil
+	}
+
+	var level int
+	if err := json.Unmarshal(data, &level); err != nil {
+		return fmt.Errorf("invalid price level: %s", data)
+	}
+	*p = PriceLevel(level)
+	return nil
+}
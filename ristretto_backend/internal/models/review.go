@@ -0,0 +1,81 @@
+package models
+
+// Review metric bounds
+const (
+	MinReviewScore = 1
+	MaxReviewScore = 5
+)
+
+// ReviewScores holds the per-metric scores of a review. A nil score means the
+// reviewer did not rate that metric (e.g. they didn't order a milk drink).
+type ReviewScores struct {
+	Espresso   *int `json:"espresso,omitempty"`
+	MilkDrinks *int `json:"milkDrinks,omitempty"`
+	Ambiance   *int `json:"ambiance,omitempty"`
+	Seating    *int `json:"seating,omitempty"`
+	Wifi       *int `json:"wifi,omitempty"`
+	Value      *int `json:"value,omitempty"`
+	Service    *int `json:"service,omitempty"`
+}
+
+// ReviewMetric is one metric's score, named by its JSON metric name
+type ReviewMetric struct {
+	Name  string
+	Score *int
+}
+
+// Metrics returns the scores in a fixed order
+func (s ReviewScores) Metrics() []ReviewMetric {
+	return []ReviewMetric{
+		{Name: "espresso", Score: s.Espresso},
+		{Name: "milkDrinks", Score: s.MilkDrinks},
+		{Name: "ambiance", Score: s.Ambiance},
+		{Name: "seating", Score: s.Seating},
+		{Name: "wifi", Score: s.Wifi},
+		{Name: "value", Score: s.Value},
+		{Name: "service", Score: s.Service},
+	}
+}
+
+// Review represents a user's review of a coffee shop
+type Review struct {
+	ID        int          `json:"id"`
+	PlaceID   string       `json:"placeId"`
+	UserID    int          `json:"userId,omitempty"` // Zero once the author's account is deleted
+	Author    string       `json:"author"`
+	Scores    ReviewScores `json:"scores"`
+	Body      string       `json:"body"`
+	CreatedAt string       `json:"createdAt"`
+	UpdatedAt string       `json:"updatedAt"`
+}
+
+// ReviewRequest represents the request body for creating or editing a review
+type ReviewRequest struct {
+	Scores ReviewScores `json:"scores"`
+	Body   string       `json:"body"`
+}
+
+// ReviewsResponse represents the response for the reviews endpoint
+type ReviewsResponse struct {
+	Reviews []Review `json:"reviews"`
+}
+
+// ReviewMetricAverages holds the average score of each metric across reviews.
+// A nil average means no reviewer has rated that metric yet.
+type ReviewMetricAverages struct {
+	Espresso   *float64 `json:"espresso,omitempty"`
+	MilkDrinks *float64 `json:"milkDrinks,omitempty"`
+	Ambiance   *float64 `json:"ambiance,omitempty"`
+	Seating    *float64 `json:"seating,omitempty"`
+	Wifi       *float64 `json:"wifi,omitempty"`
+	Value      *float64 `json:"value,omitempty"`
+	Service    *float64 `json:"service,omitempty"`
+}
+
+// RistrettoRating represents our own aggregated rating of a coffee shop
+type RistrettoRating struct {
+	Score       float64              `json:"score"`   // Bayesian-weighted overall score
+	Average     float64              `json:"average"` // Raw mean of review overall scores
+	ReviewCount int                  `json:"reviewCount"`
+	Metrics     ReviewMetricAverages `json:"metrics"`
+}
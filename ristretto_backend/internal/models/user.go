@@ -13,6 +13,7 @@ type User struct {
 	Email     string    `json:"email"`
 	FirstName string    `json:"firstName"`
 	LastName  string    `json:"lastName"`
+	Role      string    `json:"role"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 }
@@ -23,5 +24,7 @@ type ClerkClaims struct {
 	Email     string `json:"email"`
 	FirstName string `json:"firstName"`
 	LastName  string `json:"lastName"`
+	// AuthorizedParty is the origin the token was issued to
+	AuthorizedParty string `json:"azp,omitempty"`
 	jwt.RegisteredClaims
 }
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/db"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/httpclient"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
+)
+
+// CatalogSyncWorker periodically refreshes stale coffee_shops catalog entries from the places provider
+type CatalogSyncWorker struct {
+	db        *db.DB
+	places    PlacesProvider
+	interval  time.Duration
+	maxAge    time.Duration
+	batchSize int
+	logger    *slog.Logger
+}
+
+// NewCatalogSyncWorker creates a worker that every interval refreshes up to
+// batchSize catalog entries that were never synced or are older than maxAge
+func NewCatalogSyncWorker(db *db.DB, places PlacesProvider, interval, maxAge time.Duration, batchSize int, logger *slog.Logger) *CatalogSyncWorker {
+	return &CatalogSyncWorker{
+		db:        db,
+		places:    places,
+		interval:  interval,
+		maxAge:    maxAge,
+		batchSize: batchSize,
+		logger:    logger,
+	}
+}
+
+// Run syncs the catalog on every tick until the context is cancelled
+func (w *CatalogSyncWorker) Run(ctx context.Context) {
+	w.logger.Info("Catalog sync worker started", "interval", w.interval, "max_age", w.maxAge)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if synced, err := w.SyncOnce(ctx); err != nil {
+			w.logger.Error("Catalog sync failed", "err", err)
+		} else if synced > 0 {
+			w.logger.Info("Catalog sync refreshed coffee shops", "count", synced)
+		}
+
+		select {
+		case <-ctx.Done():
+			w.logger.Info("Catalog sync worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncOnce refreshes one batch of stale catalog entries and returns how many were refreshed
+func (w *CatalogSyncWorker) SyncOnce(ctx context.Context) (int, error) {
+	now := time.Now()
+	placeIDs, err := w.db.GetStaleCoffeeShops(now.Add(-w.maxAge), now.Add(-w.interval), w.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	synced := 0
+	for _, placeID := range placeIDs {
+		if ctx.Err() != nil {
+			return synced, ctx.Err()
+		}
+
+		if err := w.syncPlace(ctx, placeID); err != nil {
+			// Places that no longer exist or were never valid are never retried
+			var placesErr *PlacesAPIError
+			if errors.As(err, &placesErr) && placesErr.InvalidPlace() {
+				w.logger.InfoContext(ctx, "Coffee shop no longer exists", "place_id", placeID, "err", err)
+				if err := w.db.MarkCoffeeShopNotFound(placeID); err != nil {
+					return synced, err
+				}
+				continue
+			}
+
+			w.logger.WarnContext(ctx, "Failed to sync coffee shop", "place_id", placeID, "err", err)
+			if err := w.db.MarkCoffeeShopSyncAttempt(placeID); err != nil {
+				return synced, err
+			}
+			continue
+		}
+		synced++
+	}
+
+	return synced, nil
+}
+
+// syncPlace fetches fresh details for a place and stores them in the catalog
+func (w *CatalogSyncWorker) syncPlace(ctx context.Context, placeID string) error {
+	_, err := RefreshCoffeeShop(ctx, w.db, w.places, placeID)
+	return err
+}
+
+// RefreshCoffeeShop fetches fresh details for a place, skipping the places
+// cache, and stores them in the catalog
+func RefreshCoffeeShop(ctx context.Context, db *db.DB, places PlacesProvider, placeID string) (*models.CatalogCoffeeShop, error) {
+	details, err := places.GetPlaceDetails(WithCacheBypass(ctx), placeID)
+	if err != nil {
+		return nil, err
+	}
+
+	shop := models.NewCatalogCoffeeShop(details)
+	if err := db.UpsertCoffeeShop(shop); err != nil {
+		return nil, err
+	}
+
+	return &shop, nil
+}
+
+// ValidatePlace checks that placeID names a real place before user data is
+// attached to it, storing its details in the catalog. It returns a
+// PlacesAPIError for unknown or malformed place IDs. During a places provider
+// outage the place is accepted, leaving it to the catalog sync worker to
+// check, so an outage doesn't block favorites, visits and reviews.
+func ValidatePlace(ctx context.Context, db *db.DB, places PlacesProvider, placeID string, logger *slog.Logger) error {
+	shop, err := db.GetCoffeeShop(placeID)
+	switch {
+	case err == nil && shop.NotFoundAt != nil:
+		return &PlacesAPIError{StatusCode: http.StatusNotFound, Status: "NOT_FOUND", Message: "place no longer exists: " + placeID}
+	case err == nil && shop.LastSyncedAt != nil:
+		return nil
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		return err
+	}
+
+	details, err := places.GetPlaceDetails(ctx, placeID)
+	if err != nil {
+		if !placesOutage(err) {
+			return err
+		}
+		logger.WarnContext(ctx, "Places provider unavailable, accepting place unvalidated", "place_id", placeID, "err", err)
+		return nil
+	}
+
+	if err := db.FillCoffeeShop(models.NewCatalogCoffeeShop(details)); err != nil {
+		logger.WarnContext(ctx, "Failed to store coffee shop in catalog", "place_id", placeID, "err", err)
+	}
+	return nil
+}
+
+// placesOutage reports whether a places call failed because the provider
+// couldn't be used rather than because of the request: the budget is spent,
+// the circuit breaker is open, the provider failed or rate limited us, or the
+// call never got an answer
+func placesOutage(err error) bool {
+	var placesErr *PlacesAPIError
+	if errors.As(err, &placesErr) {
+		return placesErr.StatusCode >= http.StatusInternalServerError || placesErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	var netErr net.Error
+	return errors.Is(err, ErrPlacesBudgetExhausted) ||
+		errors.Is(err, httpclient.ErrCircuitOpen) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.As(err, &netErr)
+}
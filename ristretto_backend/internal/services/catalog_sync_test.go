@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/httpclient"
+)
+
+func TestPlaceValidationErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantOutage  bool // ValidatePlace accepts the place unvalidated
+		wantInvalid bool // The sync worker stops retrying the place
+	}{
+		{name: "not found", err: &PlacesAPIError{StatusCode: http.StatusNotFound, Status: "NOT_FOUND"}, wantInvalid: true},
+		{name: "malformed place ID", err: &PlacesAPIError{StatusCode: http.StatusBadRequest, Status: "INVALID_ARGUMENT"}, wantInvalid: true},
+		{name: "wrapped malformed place ID", err: fmt.Errorf("failed to fetch place details: %w", &PlacesAPIError{StatusCode: http.StatusBadRequest}), wantInvalid: true},
+		{name: "bad API key", err: &PlacesAPIError{StatusCode: http.StatusForbidden, Status: "PERMISSION_DENIED"}},
+		{name: "server error", err: &PlacesAPIError{StatusCode: http.StatusServiceUnavailable, Status: "UNAVAILABLE"}, wantOutage: true},
+		{name: "rate limited", err: &PlacesAPIError{StatusCode: http.StatusTooManyRequests, Status: "RESOURCE_EXHAUSTED"}, wantOutage: true},
+		{name: "budget exhausted", err: fmt.Errorf("%w: %w", ErrPlacesBudgetExhausted, httpclient.ErrNotSent), wantOutage: true},
+		{name: "circuit open", err: fmt.Errorf("failed to fetch place details: %w", httpclient.ErrCircuitOpen), wantOutage: true},
+		{name: "deadline", err: fmt.Errorf("failed to fetch place details: %w", context.DeadlineExceeded), wantOutage: true},
+		{name: "transport error", err: &url.Error{Op: "Get", URL: "https://places.googleapis.com", Err: errors.New("connection refused")}, wantOutage: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := placesOutage(tt.err); got != tt.wantOutage {
+				t.Errorf("placesOutage = %v, want %v", got, tt.wantOutage)
+			}
+
+			var placesErr *PlacesAPIError
+			invalid := errors.As(tt.err, &placesErr) && placesErr.InvalidPlace()
+			if invalid != tt.wantInvalid {
+				t.Errorf("InvalidPlace = %v, want %v", invalid, tt.wantInvalid)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/pkg/utils"
+)
+
+// FixturePlacesProvider serves places from a local fixture file so the app
+// can be developed and tested without calling Google
+type FixturePlacesProvider struct {
+	places []models.PlaceDetails
+}
+
+// fixtureFile is the Google-style fixture format: {"places": [PlaceDetails...]}
+type fixtureFile struct {
+	Type     string                `json:"type"`
+	Places   []models.PlaceDetails `json:"places"`
+	Features []geoJSONFeature      `json:"features"`
+}
+
+// geoJSONFeature is a GeoJSON Point feature describing a place
+type geoJSONFeature struct {
+	Geometry struct {
+		Type        string    `json:"type"`
+		Coordinates []float64 `json:"coordinates"` // [longitude, latitude]
+	} `json:"geometry"`
+	Properties struct {
+		ID            string   `json:"id"`
+		Name          string   `json:"name"`
+		Address       string   `json:"address"`
+		PhoneNumber   string   `json:"phoneNumber"`
+		Website       string   `json:"website"`
+		GoogleMapsURI string   `json:"googleMapsUri"`
+		Rating        float64  `json:"rating"`
+		PriceLevel    int      `json:"priceLevel"`
+		Photos        []string `json:"photos"`
+	} `json:"properties"`
+}
+
+// NewFixturePlacesProvider loads places from a JSON fixture file. The file may
+// either mirror the Google Places response shape ({"places": [...]}) or be a
+// GeoJSON FeatureCollection of Point features.
+func NewFixturePlacesProvider(path string) (*FixturePlacesProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read places fixture: %w", err)
+	}
+
+	var file fixtureFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse places fixture: %w", err)
+	}
+
+	places := file.Places
+	if file.Type == "FeatureCollection" {
+		places, err = placesFromGeoJSON(file.Features)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, place := range places {
+		if place.PlaceID == "" {
+			return nil, fmt.Errorf("places fixture contains a place without an id")
+		}
+	}
+
+	return &FixturePlacesProvider{
+		places: places,
+	}, nil
+}
+
+// placesFromGeoJSON converts GeoJSON Point features into place details
+func placesFromGeoJSON(features []geoJSONFeature) ([]models.PlaceDetails, error) {
+	places := make([]models.PlaceDetails, 0, len(features))
+
+	for i, feature := range features {
+		if feature.Geometry.Type != "Point" || len(feature.Geometry.Coordinates) < 2 {
+			return nil, fmt.Errorf("places fixture feature %d is not a Point", i)
+		}
+
+		props := feature.Properties
+		place := models.PlaceDetails{
+			PlaceID:                  props.ID,
+			DisplayName:              models.DisplayName{Text: props.Name},
+			FormattedAddress:         props.Address,
+			InternationalPhoneNumber: props.PhoneNumber,
+			WebsiteURI:               props.Website,
+			GoogleMapsURI:            props.GoogleMapsURI,
+			Rating:                   props.Rating,
+			PriceLevel:               models.PriceLevel(props.PriceLevel),
+			Location: models.Location{
+				Latitude:  feature.Geometry.Coordinates[1],
+				Longitude: feature.Geometry.Coordinates[0],
+			},
+		}
+		for _, photo := range props.Photos {
+			place.Photos = append(place.Photos, &models.Photo{Name: photo})
+		}
+
+		places = append(places, place)
+	}
+
+	return places, nil
+}
+
+// SearchNearby returns fixture places within radius meters of a location, nearest first
+func (p *FixturePlacesProvider) SearchNearby(ctx context.Context, latitude, longitude, radius float64, maxResults int) ([]models.Place, error) {
+	type candidate struct {
+		place    models.PlaceDetails
+		distance float64
+	}
+
+	var candidates []candidate
+	for _, place := range p.places {
+		distance := utils.DistanceMeters(latitude, longitude, place.Location.Latitude, place.Location.Longitude)
+		if distance <= radius {
+			candidates = append(candidates, candidate{place: place, distance: distance})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	if maxResults > 0 && len(candidates) > maxResults {
+		candidates = candidates[:maxResults]
+	}
+
+	places := make([]models.Place, 0, len(candidates))
+	for _, c := range candidates {
+		places = append(places, fixturePlace(c.place))
+	}
+
+	return places, nil
+}
+
+// SearchText returns fixture places whose name or address contains every word of the query
+func (p *FixturePlacesProvider) SearchText(ctx context.Context, query string, bias *models.Circle, maxResults int) ([]models.Place, error) {
+	words := strings.Fields(strings.ToLower(query))
+
+	var places []models.Place
+	for _, place := range p.places {
+		text := strings.ToLower(place.DisplayName.Text + " " + place.FormattedAddress)
+		matches := len(words) > 0
+		for _, word := range words {
+			if !strings.Contains(text, word) {
+				matches = false
+				break
+			}
+		}
+		if !matches {
+			continue
+		}
+
+		places = append(places, fixturePlace(place))
+		if maxResults > 0 && len(places) == maxResults {
+			break
+		}
+	}
+
+	return places, nil
+}
+
+// Autocomplete returns fixture places with a word in their name starting with the input
+func (p *FixturePlacesProvider) Autocomplete(ctx context.Context, input string, bias *models.Circle, sessionToken string) ([]models.PlaceSuggestion, error) {
+	input = strings.ToLower(strings.TrimSpace(input))
+
+	var suggestions []models.PlaceSuggestion
+	for _, place := range p.places {
+		name := strings.ToLower(place.DisplayName.Text)
+		matches := strings.HasPrefix(name, input)
+		for _, word := range strings.Fields(name) {
+			matches = matches || strings.HasPrefix(word, input)
+		}
+		if input == "" || !matches {
+			continue
+		}
+
+		suggestions = append(suggestions, models.PlaceSuggestion{
+			PlaceID:       place.PlaceID,
+			Name:          place.DisplayName.Text,
+			SecondaryText: place.FormattedAddress,
+		})
+	}
+
+	return suggestions, nil
+}
+
+// fixturePlace converts fixture place details into a search result
+func fixturePlace(details models.PlaceDetails) models.Place {
+	var place models.Place
+	place.Name = "places/" + details.PlaceID
+	place.PlaceID = details.PlaceID
+	place.DisplayName.Text = details.DisplayName.Text
+	place.Location.Latitude = details.Location.Latitude
+	place.Location.Longitude = details.Location.Longitude
+	place.Rating = details.Rating
+	place.PriceLevel = details.PriceLevel
+	if details.CurrentOpeningHours != nil {
+		place.CurrentOpeningHours = &models.OpenStatus{OpenNow: details.CurrentOpeningHours.OpenNow}
+	}
+	return place
+}
+
+// GetPlaceDetails returns the fixture place with the given ID
+func (p *FixturePlacesProvider) GetPlaceDetails(ctx context.Context, placeID string) (*models.PlaceDetails, error) {
+	for _, place := range p.places {
+		if place.PlaceID == placeID {
+			details := place
+			return &details, nil
+		}
+	}
+
+	return nil, &PlacesAPIError{
+		StatusCode: http.StatusNotFound,
+		Status:     "NOT_FOUND",
+		Message:    "place not found in fixture: " + placeID,
+	}
+}
+
+// GetPhotoURL returns fixture photo names that are already absolute URLs.
+// Fixtures have no Google photo resources to resolve.
+func (p *FixturePlacesProvider) GetPhotoURL(photoName string, size string) string {
+	if strings.HasPrefix(photoName, "http://") || strings.HasPrefix(photoName, "https://") {
+		return photoName
+	}
+	return ""
+}
+
+// TransformPhotoURLs converts fixture photo names to URLs
+func (p *FixturePlacesProvider) TransformPhotoURLs(photoNames []string, size string) []string {
+	if len(photoNames) == 0 {
+		return nil
+	}
+
+	photoURLs := make([]string, 0, len(photoNames))
+	for _, name := range photoNames {
+		if url := p.GetPhotoURL(name, size); url != "" {
+			photoURLs = append(photoURLs, url)
+		}
+	}
+
+	return photoURLs
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFixture writes a places fixture to a temporary file
+func writeFixture(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "places.json")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	return path
+}
+
+// nearbyFixture places cafes north of (34, -118), listed out of distance order.
+// 0.001 degrees of latitude is about 111 meters.
+const nearbyFixture = `{
+	"type": "FeatureCollection",
+	"features": [
+		{"geometry": {"type": "Point", "coordinates": [-118, 34.003]}, "properties": {"id": "mid", "name": "Mid"}},
+		{"geometry": {"type": "Point", "coordinates": [-118, 34.02]}, "properties": {"id": "far", "name": "Far"}},
+		{"geometry": {"type": "Point", "coordinates": [-118, 34.001]}, "properties": {"id": "near", "name": "Near"}}
+	]
+}`
+
+func TestFixtureSearchNearbyFiltersAndSortsByDistance(t *testing.T) {
+	provider, err := NewFixturePlacesProvider(writeFixture(t, nearbyFixture))
+	if err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		radius     float64
+		maxResults int
+		want       []string
+	}{
+		{name: "within radius, nearest first", radius: 1000, want: []string{"near", "mid"}},
+		{name: "everything", radius: 5000, want: []string{"near", "mid", "far"}},
+		{name: "capped to max results", radius: 5000, maxResults: 2, want: []string{"near", "mid"}},
+		{name: "nothing in range", radius: 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			places, err := provider.SearchNearby(context.Background(), 34, -118, tt.radius, tt.maxResults)
+			if err != nil {
+				t.Fatalf("search nearby: %v", err)
+			}
+
+			got := make([]string, 0, len(places))
+			for _, place := range places {
+				got = append(got, place.PlaceID)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFixtureBundledFileLoads(t *testing.T) {
+	provider, err := NewFixturePlacesProvider("../../fixtures/places_la.json")
+	if err != nil {
+		t.Fatalf("load bundled fixture: %v", err)
+	}
+	if len(provider.places) == 0 {
+		t.Fatal("bundled fixture has no places")
+	}
+}
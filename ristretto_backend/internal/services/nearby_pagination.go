@@ -0,0 +1,312 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/pkg/utils"
+)
+
+const (
+	// NearbyResultCap is the most results Google returns for a single nearby search
+	NearbyResultCap = 20
+	// minTiledRadius is the smallest tile radius, in meters, that is split again
+	minTiledRadius = 200.0
+	// tileOverlap enlarges each tile's search radius so that places near tile
+	// edges are still found even though search results are approximate.
+	// Ownership by nearest tile center keeps the overlap from causing duplicates.
+	tileOverlap = 1.25
+	// maxNearbyTileDepth is how many times the search circle can be split
+	maxNearbyTileDepth = 4
+	// maxNearbySearchesPerPage bounds the provider calls made for one page.
+	// Dense areas and selective filters can leave a page short; its cursor
+	// resumes the walk.
+	maxNearbySearchesPerPage = 4
+	// metersPerDegreeLatitude is the approximate length of one degree of latitude
+	metersPerDegreeLatitude = 111320.0
+)
+
+// NearbyCursor identifies where the next page of a tiled nearby search starts.
+// It is handed to clients as an opaque string.
+type NearbyCursor struct {
+	Latitude  float64 `json:"lat"`
+	Longitude float64 `json:"lng"`
+	Radius    float64 `json:"r"`
+	Tile      []int   `json:"t,omitempty"` // Tile index at each level, from the top level down
+	Offset    int     `json:"o"`           // Place to resume at within the tile
+}
+
+// NearbyPage is one page of nearby search results
+type NearbyPage struct {
+	Places     []models.Place
+	NextCursor string // Empty on the last page
+}
+
+// Encode returns the opaque string form of the cursor
+func (c NearbyCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeNearbyCursor parses a cursor produced by NearbyCursor.Encode. Callers
+// must still check the search circle against their own bounds.
+func DecodeNearbyCursor(s string) (NearbyCursor, error) {
+	var c NearbyCursor
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, fmt.Errorf("invalid cursor")
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("invalid cursor")
+	}
+	if c.Radius <= 0 || c.Offset < 0 || len(c.Tile) > maxNearbyTileDepth+1 {
+		return c, fmt.Errorf("invalid cursor")
+	}
+	for i, t := range c.Tile {
+		// The top level is the whole circle; every split makes seven tiles
+		tiles := 7
+		if i == 0 {
+			tiles = 1
+		}
+		if t < 0 || t >= tiles {
+			return c, fmt.Errorf("invalid cursor")
+		}
+	}
+
+	return c, nil
+}
+
+// SearchNearbyPage returns one page of coffee shops within the cursor's
+// search circle that pass include, which may be nil.
+//
+// The whole circle is searched first. Because Google caps each nearby search
+// at NearbyResultCap results, a search that comes back full is split into
+// hexagonally packed tiles that are searched in turn, and so on down, so dense
+// areas aren't cut short. Every place is assigned to the tile whose center it
+// is nearest to at each level, so results are de-duplicated across tiles and
+// pages without the cursor having to remember what was already returned.
+//
+// Results are ordered by distance within each tile that was searched. When
+// the circle had to be split, tiles come in order, center tile first, so
+// results are only roughly nearest first across the whole circle.
+func SearchNearbyPage(ctx context.Context, provider PlacesProvider, cursor NearbyCursor, pageSize int, include func(models.Place) bool) (NearbyPage, error) {
+	search := &nearbySearch{
+		ctx:      ctx,
+		provider: provider,
+		cursor:   cursor,
+		pageSize: pageSize,
+		include:  include,
+		page:     NearbyPage{Places: make([]models.Place, 0, pageSize)},
+	}
+
+	circle := models.Circle{
+		Center: models.Center{Latitude: cursor.Latitude, Longitude: cursor.Longitude},
+		Radius: cursor.Radius,
+	}
+	levels := [][]models.Circle{{circle}}
+	_, err := search.walk(levels, nil, cursor.Tile)
+	return search.page, err
+}
+
+// nearbySearch is the state of filling one page of a tiled nearby search
+type nearbySearch struct {
+	ctx      context.Context
+	provider PlacesProvider
+	cursor   NearbyCursor
+	pageSize int
+	include  func(models.Place) bool
+	searches int
+	page     NearbyPage
+}
+
+// walk searches the tiles of the deepest level in order, starting at the
+// resume path, and descends into tiles that hit the result cap. Tiles above
+// the end of the resume path already hit the cap on an earlier page, so they
+// are descended into without searching them again. It reports whether the
+// page is done.
+func (s *nearbySearch) walk(levels [][]models.Circle, path []int, resume []int) (bool, error) {
+	tiles := levels[len(levels)-1]
+
+	start := 0
+	if len(resume) > 0 {
+		start = resume[0]
+	}
+
+	for t := start; t < len(tiles); t++ {
+		tilePath := append(path[:len(path):len(path)], t)
+		resuming := len(resume) > 0 && t == start
+		children, splittable := subdivideTile(tiles[t], len(levels))
+
+		var results []models.Place
+		if !resuming || len(resume) == 1 || !splittable {
+			if s.searches == maxNearbySearchesPerPage {
+				// Leave the rest to the next page
+				s.setNextCursor(tilePath, 0)
+				return true, nil
+			}
+			s.searches++
+
+			// Tiles below the top level overlap their neighbours
+			radius := tiles[t].Radius
+			if len(levels) > 1 {
+				radius *= tileOverlap
+			}
+
+			var err error
+			results, err = s.provider.SearchNearby(s.ctx, tiles[t].Center.Latitude, tiles[t].Center.Longitude, radius, NearbyResultCap)
+			if err != nil {
+				return true, err
+			}
+		}
+
+		if splittable && (results == nil || len(results) >= NearbyResultCap) {
+			var rest []int
+			if resuming {
+				rest = resume[1:]
+			}
+			childLevels := append(levels[:len(levels):len(levels)], children)
+			if done, err := s.walk(childLevels, tilePath, rest); done || err != nil {
+				return true, err
+			}
+			continue
+		}
+
+		owned := s.ownedPlaces(levels, tilePath, results)
+
+		offset := 0
+		if resuming && len(resume) == 1 {
+			offset = s.cursor.Offset
+		}
+
+		for i := offset; i < len(owned); i++ {
+			s.page.Places = append(s.page.Places, owned[i])
+			if len(s.page.Places) < s.pageSize {
+				continue
+			}
+
+			// The page is full; point the cursor at the next unreturned place
+			if i+1 < len(owned) || !lastTile(levels, tilePath) {
+				s.setNextCursor(tilePath, i+1)
+			}
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// setNextCursor points the next page at a place within a tile. An offset
+// past the tile's last place moves on to the tile after it.
+func (s *nearbySearch) setNextCursor(tilePath []int, offset int) {
+	next := s.cursor
+	next.Tile, next.Offset = tilePath, offset
+	s.page.NextCursor = next.Encode()
+}
+
+// nearbyTiles splits a search circle into a center tile surrounded by six
+// tiles, each with half the radius. Their hexagonal cells exactly cover the
+// original circle; tileOverlap is added when the tiles are searched.
+func nearbyTiles(latitude, longitude, radius float64) []models.Circle {
+	tileRadius := radius / 2
+	ringDistance := tileRadius * math.Sqrt(3)
+
+	tiles := []models.Circle{{
+		Center: models.Center{Latitude: latitude, Longitude: longitude},
+		Radius: tileRadius,
+	}}
+	for i := 0; i < 6; i++ {
+		bearing := float64(i) * math.Pi / 3
+		dLat := ringDistance * math.Cos(bearing) / metersPerDegreeLatitude
+		dLng := ringDistance * math.Sin(bearing) / (metersPerDegreeLatitude * math.Cos(latitude*math.Pi/180))
+		tiles = append(tiles, models.Circle{
+			Center: models.Center{Latitude: latitude + dLat, Longitude: longitude + dLng},
+			Radius: tileRadius,
+		})
+	}
+
+	return tiles
+}
+
+// ownedPlaces filters a tile's results down to places inside the original
+// circle whose nearest tile is this one at every level and that pass the
+// include filter, ordered by distance from the center
+func (s *nearbySearch) ownedPlaces(levels [][]models.Circle, tilePath []int, results []models.Place) []models.Place {
+	type candidate struct {
+		place    models.Place
+		distance float64
+	}
+
+	var candidates []candidate
+	for _, place := range results {
+		lat, lng := place.Location.Latitude, place.Location.Longitude
+
+		distance := utils.DistanceMeters(s.cursor.Latitude, s.cursor.Longitude, lat, lng)
+		if distance > s.cursor.Radius || !ownedBy(levels, tilePath, lat, lng) {
+			continue
+		}
+		if s.include != nil && !s.include(place) {
+			continue
+		}
+		candidates = append(candidates, candidate{place: place, distance: distance})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].place.PlaceID < candidates[j].place.PlaceID
+	})
+
+	owned := make([]models.Place, 0, len(candidates))
+	for _, c := range candidates {
+		owned = append(owned, c.place)
+	}
+	return owned
+}
+
+// subdivideTile splits a tile at the given depth into smaller tiles covering
+// its cell. Tiles too small or too deep to split report false.
+func subdivideTile(tile models.Circle, depth int) ([]models.Circle, bool) {
+	if depth > maxNearbyTileDepth || tile.Radius < minTiledRadius {
+		return nil, false
+	}
+	return nearbyTiles(tile.Center.Latitude, tile.Center.Longitude, tile.Radius), true
+}
+
+// lastTile reports whether a tile path is the last tile at every level
+func lastTile(levels [][]models.Circle, tilePath []int) bool {
+	for i, t := range tilePath {
+		if t != len(levels[i])-1 {
+			return false
+		}
+	}
+	return true
+}
+
+// ownedBy reports whether a point's nearest tile is the one on tilePath at
+// every level
+func ownedBy(levels [][]models.Circle, tilePath []int, latitude, longitude float64) bool {
+	for i, t := range tilePath {
+		if nearestTile(levels[i], latitude, longitude) != t {
+			return false
+		}
+	}
+	return true
+}
+
+// nearestTile returns the index of the tile whose center is closest to a point
+func nearestTile(tiles []models.Circle, latitude, longitude float64) int {
+	nearest, best := 0, math.Inf(1)
+	for i, tile := range tiles {
+		if d := utils.DistanceMeters(tile.Center.Latitude, tile.Center.Longitude, latitude, longitude); d < best {
+			nearest, best = i, d
+		}
+	}
+	return nearest
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/pkg/utils"
+)
+
+// gridProvider returns a fixture provider with a cafe every spacing degrees
+// in a square of half-width span around (latitude, longitude)
+func gridProvider(latitude, longitude, span, spacing float64) *FixturePlacesProvider {
+	provider := &FixturePlacesProvider{}
+	steps := int(span / spacing)
+	for i := -steps; i <= steps; i++ {
+		for j := -steps; j <= steps; j++ {
+			provider.places = append(provider.places, models.PlaceDetails{
+				PlaceID: fmt.Sprintf("cafe_%d_%d", i, j),
+				Location: models.Location{
+					Latitude:  latitude + float64(i)*spacing,
+					Longitude: longitude + float64(j)*spacing,
+				},
+			})
+		}
+	}
+	return provider
+}
+
+// countingProvider counts the nearby searches made through it
+type countingProvider struct {
+	PlacesProvider
+	searches int
+}
+
+func (p *countingProvider) SearchNearby(ctx context.Context, latitude, longitude, radius float64, maxResults int) ([]models.Place, error) {
+	p.searches++
+	return p.PlacesProvider.SearchNearby(ctx, latitude, longitude, radius, maxResults)
+}
+
+// collectNearby pages through a nearby search, returning how often each place was seen
+func collectNearby(t *testing.T, provider PlacesProvider, cursor NearbyCursor, pageSize int, include func(models.Place) bool) map[string]int {
+	t.Helper()
+
+	seen := make(map[string]int)
+	for pages := 0; ; pages++ {
+		if pages == 1000 {
+			t.Fatal("pagination did not finish")
+		}
+
+		page, err := SearchNearbyPage(context.Background(), provider, cursor, pageSize, include)
+		if err != nil {
+			t.Fatalf("search page %d: %v", pages, err)
+		}
+		if len(page.Places) > pageSize {
+			t.Fatalf("page %d has %d places, want at most %d", pages, len(page.Places), pageSize)
+		}
+		for _, place := range page.Places {
+			seen[place.PlaceID]++
+		}
+
+		if page.NextCursor == "" {
+			return seen
+		}
+		if cursor, err = DecodeNearbyCursor(page.NextCursor); err != nil {
+			t.Fatalf("decode cursor from page %d: %v", pages, err)
+		}
+	}
+}
+
+func TestSearchNearbyPageCoversDenseAreas(t *testing.T) {
+	// About 700 cafes within the circle, far more than one search returns
+	// from the circle or any of its first tiles
+	provider := gridProvider(34, -118, 0.03, 0.002)
+	cursor := NearbyCursor{Latitude: 34, Longitude: -118, Radius: 3000}
+
+	seen := collectNearby(t, provider, cursor, 50, nil)
+
+	want := 0
+	for _, place := range provider.places {
+		if utils.DistanceMeters(34, -118, place.Location.Latitude, place.Location.Longitude) > cursor.Radius {
+			continue
+		}
+		want++
+		if n := seen[place.PlaceID]; n != 1 {
+			t.Errorf("%s returned %d times, want once", place.PlaceID, n)
+		}
+	}
+	if len(seen) != want {
+		t.Errorf("returned %d places, want %d", len(seen), want)
+	}
+}
+
+func TestSearchNearbyPageSearchesSparseAreasOnce(t *testing.T) {
+	// Nine cafes, well under what one search returns
+	provider := &countingProvider{PlacesProvider: gridProvider(34, -118, 0.002, 0.002)}
+	cursor := NearbyCursor{Latitude: 34, Longitude: -118, Radius: 3000}
+
+	page, err := SearchNearbyPage(context.Background(), provider, cursor, 50, nil)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if provider.searches != 1 {
+		t.Errorf("made %d searches, want 1", provider.searches)
+	}
+	if len(page.Places) != 9 || page.NextCursor != "" {
+		t.Fatalf("page has %d places and cursor %q, want 9 places and no cursor", len(page.Places), page.NextCursor)
+	}
+
+	// A single search is ordered nearest first
+	for i := 1; i < len(page.Places); i++ {
+		prev, place := page.Places[i-1].Location, page.Places[i].Location
+		if utils.DistanceMeters(34, -118, prev.Latitude, prev.Longitude) > utils.DistanceMeters(34, -118, place.Latitude, place.Longitude) {
+			t.Errorf("place %d is nearer than place %d", i, i-1)
+		}
+	}
+}
+
+func TestSearchNearbyPageBoundsSearchesPerPage(t *testing.T) {
+	provider := &countingProvider{PlacesProvider: gridProvider(34, -118, 0.03, 0.002)}
+	cursor := NearbyCursor{Latitude: 34, Longitude: -118, Radius: 3000}
+
+	for pages := 0; pages < 1000; pages++ {
+		provider.searches = 0
+		page, err := SearchNearbyPage(context.Background(), provider, cursor, 50, nil)
+		if err != nil {
+			t.Fatalf("search page %d: %v", pages, err)
+		}
+		if provider.searches > maxNearbySearchesPerPage {
+			t.Fatalf("page %d made %d searches, want at most %d", pages, provider.searches, maxNearbySearchesPerPage)
+		}
+		if page.NextCursor == "" {
+			return
+		}
+		if cursor, err = DecodeNearbyCursor(page.NextCursor); err != nil {
+			t.Fatalf("decode cursor from page %d: %v", pages, err)
+		}
+	}
+	t.Fatal("pagination did not finish")
+}
+
+func TestSearchNearbyPageFiltersBeforeFillingPages(t *testing.T) {
+	provider := gridProvider(34, -118, 0.01, 0.001)
+	cursor := NearbyCursor{Latitude: 34, Longitude: -118, Radius: 800}
+	include := func(place models.Place) bool {
+		return strings.HasSuffix(place.PlaceID, "_0")
+	}
+
+	// Most nearby places don't match, so the first page runs out of searches
+	// before it fills; only matching places are on it, and its cursor carries on
+	page, err := SearchNearbyPage(context.Background(), provider, cursor, 5, include)
+	if err != nil {
+		t.Fatalf("search first page: %v", err)
+	}
+	if len(page.Places) == 0 || page.NextCursor == "" {
+		t.Fatalf("first page has %d places and cursor %q, want places and a cursor", len(page.Places), page.NextCursor)
+	}
+	for _, place := range page.Places {
+		if !include(place) {
+			t.Errorf("first page includes %s", place.PlaceID)
+		}
+	}
+
+	seen := collectNearby(t, provider, cursor, 5, include)
+
+	want := 0
+	for _, place := range provider.places {
+		if !include(fixturePlace(place)) || utils.DistanceMeters(34, -118, place.Location.Latitude, place.Location.Longitude) > cursor.Radius {
+			continue
+		}
+		want++
+		if n := seen[place.PlaceID]; n != 1 {
+			t.Errorf("%s returned %d times, want once", place.PlaceID, n)
+		}
+	}
+	if len(seen) != want {
+		t.Errorf("returned %d places, want %d", len(seen), want)
+	}
+}
+
+func TestDecodeNearbyCursorRejectsInvalidTiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		cursor  NearbyCursor
+		wantErr bool
+	}{
+		{name: "start", cursor: NearbyCursor{Latitude: 34, Longitude: -118, Radius: 1000}},
+		{name: "subdivided tile", cursor: NearbyCursor{Latitude: 34, Longitude: -118, Radius: 1000, Tile: []int{0, 6, 3}, Offset: 2}},
+		{name: "tile out of range", cursor: NearbyCursor{Latitude: 34, Longitude: -118, Radius: 1000, Tile: []int{0, 7}}, wantErr: true},
+		{name: "top level out of range", cursor: NearbyCursor{Latitude: 34, Longitude: -118, Radius: 1000, Tile: []int{1}}, wantErr: true},
+		{name: "too deep", cursor: NearbyCursor{Latitude: 34, Longitude: -118, Radius: 1000, Tile: make([]int, maxNearbyTileDepth+2)}, wantErr: true},
+		{name: "negative offset", cursor: NearbyCursor{Latitude: 34, Longitude: -118, Radius: 1000, Offset: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeNearbyCursor(tt.cursor.Encode())
+			if tt.wantErr && err == nil {
+				t.Error("cursor was accepted")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("cursor was rejected: %v", err)
+			}
+		})
+	}
+}
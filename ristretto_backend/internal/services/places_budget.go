@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/httpclient"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/metrics"
+)
+
+// ErrPlacesBudgetExhausted is returned instead of calling the Google Places
+// API once the daily call budget is spent
+var ErrPlacesBudgetExhausted = errors.New("daily Google Places API call budget exhausted")
+
+// BudgetCounter counts calls against a daily limit
+type BudgetCounter interface {
+	// TakePlacesCall counts one call on day, unless limit calls were already
+	// made that day, and reports whether it was counted
+	TakePlacesCall(ctx context.Context, day string, limit int) (bool, error)
+}
+
+// PlacesBudget caps the number of billable Google Places calls per day. With
+// a shared counter such as Postgres, the cap applies across every server
+// instance.
+type PlacesBudget struct {
+	limit    int
+	location *time.Location // Timezone the day boundary is taken in
+	counter  BudgetCounter
+	now      func() time.Time
+}
+
+// NewPlacesBudget creates a budget of limit calls per day in location,
+// counted by counter. A nil counter counts in memory, per server instance. A
+// limit of zero or less is unlimited.
+func NewPlacesBudget(limit int, location *time.Location, counter BudgetCounter) *PlacesBudget {
+	if counter == nil {
+		counter = &memoryBudgetCounter{}
+	}
+	return &PlacesBudget{
+		limit:    limit,
+		location: location,
+		counter:  counter,
+		now:      time.Now,
+	}
+}
+
+// Take spends one call, or returns ErrPlacesBudgetExhausted if none are left
+// today. Other errors mean the counter couldn't be reached. A nil budget is
+// unlimited.
+func (b *PlacesBudget) Take(ctx context.Context) error {
+	if b == nil || b.limit <= 0 {
+		return nil
+	}
+
+	day := b.now().In(b.location).Format(time.DateOnly)
+	ok, err := b.counter.TakePlacesCall(ctx, day, b.limit)
+	if err != nil {
+		return fmt.Errorf("failed to count Places call: %w", err)
+	}
+	if !ok {
+		return ErrPlacesBudgetExhausted
+	}
+	return nil
+}
+
+// memoryBudgetCounter is a BudgetCounter local to one server instance
+type memoryBudgetCounter struct {
+	mu   sync.Mutex
+	day  string
+	used int
+}
+
+// TakePlacesCall counts one call on day unless limit is reached
+func (c *memoryBudgetCounter) TakePlacesCall(ctx context.Context, day string, limit int) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if day != c.day {
+		c.day = day
+		c.used = 0
+	}
+	if c.used >= limit {
+		return false, nil
+	}
+	c.used++
+	return true, nil
+}
+
+// unbilledKey marks a request context as not billed by Google
+type unbilledKey struct{}
+
+// withUnbilled marks requests made with ctx as free, so they don't spend the budget
+func withUnbilled(ctx context.Context) context.Context {
+	return context.WithValue(ctx, unbilledKey{}, true)
+}
+
+// budgetTransport spends the budget on every request sent upstream,
+// including retries and redirects, so the count matches what Google bills
+type budgetTransport struct {
+	next    http.RoundTripper
+	budget  *PlacesBudget
+	metrics *metrics.PlacesMetrics
+	logger  *slog.Logger
+}
+
+// newBudgetTransport wraps next so requests are refused once budget is spent
+func newBudgetTransport(next http.RoundTripper, budget *PlacesBudget, placesMetrics *metrics.PlacesMetrics, logger *slog.Logger) http.RoundTripper {
+	return &budgetTransport{next: next, budget: budget, metrics: placesMetrics, logger: logger}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *budgetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if unbilled, _ := ctx.Value(unbilledKey{}).(bool); !unbilled {
+		err := t.budget.Take(ctx)
+		switch {
+		case errors.Is(err, ErrPlacesBudgetExhausted):
+			t.metrics.BudgetRejected(ctx)
+			t.logger.WarnContext(ctx, "Refusing Google Places API call", "err", err)
+			return nil, fmt.Errorf("%w: %w", err, httpclient.ErrNotSent)
+		case err != nil:
+			// Keep serving users if the counter is down; the budget is a guardrail
+			t.logger.WarnContext(ctx, "Places budget unavailable, allowing call", "err", err)
+		}
+	}
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/httpclient"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/metrics"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/pkg/utils"
+)
+
+const (
+	// nearbyRadiusBucket is the granularity, in meters, that nearby search radii are rounded up to
+	nearbyRadiusBucket = 50.0
+	// maxNearbyRadius is the largest radius, in meters, Google accepts for a nearby search
+	maxNearbyRadius = 50000.0
+)
+
+// CacheEntry is a cached value with its expiry time. Backends may return
+// expired entries; it is up to the caller to decide whether they are usable.
+type CacheEntry struct {
+	Value     []byte
+	ExpiresAt time.Time
+}
+
+// Expired reports whether the entry is past its TTL
+func (e CacheEntry) Expired() bool {
+	return time.Now().After(e.ExpiresAt)
+}
+
+// CacheBackend stores cached places responses
+type CacheBackend interface {
+	Get(ctx context.Context, key string) (CacheEntry, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// CacheTTLs configures how long each type of places call is cached
+type CacheTTLs struct {
+	Nearby  time.Duration
+	Details time.Duration
+	Stale   time.Duration // How long past expiry an entry may still be served; zero means no limit
+}
+
+// Call types labeling places cache metrics
+const (
+	cacheCallNearby  = "nearby"
+	cacheCallDetails = "details"
+)
+
+// cacheBypassKey is the context key marking a request that must skip the cache
+type cacheBypassKey struct{}
+
+// WithCacheBypass returns a context that makes CachedPlacesProvider skip
+// cache lookups. Fresh results are still written back to the cache.
+func WithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+// cacheBypassed reports whether the context asks to skip the cache
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+// CachedPlacesProvider is a read-through cache in front of another PlacesProvider
+type CachedPlacesProvider struct {
+	PlacesProvider
+	backend CacheBackend
+	ttls    CacheTTLs
+	metrics *metrics.PlacesCacheMetrics
+	logger  *slog.Logger
+}
+
+// NewCachedPlacesProvider wraps a provider with a read-through cache, recording
+// hits and misses in cacheMetrics
+func NewCachedPlacesProvider(provider PlacesProvider, backend CacheBackend, ttls CacheTTLs, cacheMetrics *metrics.PlacesCacheMetrics, logger *slog.Logger) *CachedPlacesProvider {
+	return &CachedPlacesProvider{
+		PlacesProvider: provider,
+		backend:        backend,
+		ttls:           ttls,
+		metrics:        cacheMetrics,
+		logger:         logger,
+	}
+}
+
+// FetchPhoto fetches a photo from the wrapped provider, if it supports it.
+// Photos are cached separately by the photo proxy.
+func (c *CachedPlacesProvider) FetchPhoto(ctx context.Context, photoName string, size PhotoSize) (*PhotoMedia, error) {
+	if fetcher, ok := c.PlacesProvider.(PhotoFetcher); ok {
+		return fetcher.FetchPhoto(ctx, photoName, size)
+	}
+	return nil, ErrPhotosUnsupported
+}
+
+// Ping checks the wrapped provider, if it supports it
+func (c *CachedPlacesProvider) Ping(ctx context.Context) error {
+	if pinger, ok := c.PlacesProvider.(Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// SearchNearby serves nearby searches from the cache. The center is snapped
+// to a geohash cell and the radius rounded up to a bucket, so that requests
+// from nearly the same spot share one cache entry and one upstream call. The
+// radius is grown by how far the center moved, so the snapped search covers
+// the caller's circle, and results are filtered back down to that circle.
+func (c *CachedPlacesProvider) SearchNearby(ctx context.Context, latitude, longitude, radius float64, maxResults int) ([]models.Place, error) {
+	hash, snappedLat, snappedLng := utils.GeohashCenter(latitude, longitude, geohashPrecision(radius))
+	offset := utils.DistanceMeters(latitude, longitude, snappedLat, snappedLng)
+	searchRadius := math.Min(math.Ceil((radius+offset)/nearbyRadiusBucket)*nearbyRadiusBucket, maxNearbyRadius)
+	key := fmt.Sprintf("nearby:%s:%.0f:%d", hash, searchRadius, maxResults)
+
+	var places []models.Place
+	if c.lookup(ctx, cacheCallNearby, key, &places) {
+		return withinCircle(places, latitude, longitude, radius, maxResults), nil
+	}
+
+	places, err := c.PlacesProvider.SearchNearby(ctx, snappedLat, snappedLng, searchRadius, maxResults)
+	if err != nil {
+		if c.serveStale(ctx, cacheCallNearby, err, key, &places) {
+			return withinCircle(places, latitude, longitude, radius, maxResults), nil
+		}
+		return nil, err
+	}
+
+	c.store(ctx, key, places, c.ttls.Nearby)
+	return withinCircle(places, latitude, longitude, radius, maxResults), nil
+}
+
+// withinCircle filters the results of a snapped nearby search down to the
+// caller's circle. Full results are returned as they are: the provider had
+// more places than it returned, and callers that split searches on
+// NearbyResultCap must still see that.
+func withinCircle(places []models.Place, latitude, longitude, radius float64, maxResults int) []models.Place {
+	if len(places) >= maxResults {
+		return places
+	}
+
+	within := make([]models.Place, 0, len(places))
+	for _, place := range places {
+		if utils.DistanceMeters(latitude, longitude, place.Location.Latitude, place.Location.Longitude) <= radius {
+			within = append(within, place)
+		}
+	}
+	return within
+}
+
+// GetPlaceDetails serves place details from the cache, keyed on place ID
+func (c *CachedPlacesProvider) GetPlaceDetails(ctx context.Context, placeID string) (*models.PlaceDetails, error) {
+	key := "details:" + placeID
+
+	var details models.PlaceDetails
+	if c.lookup(ctx, cacheCallDetails, key, &details) {
+		return &details, nil
+	}
+
+	fresh, err := c.PlacesProvider.GetPlaceDetails(ctx, placeID)
+	if err != nil {
+		if c.serveStale(ctx, cacheCallDetails, err, key, &details) {
+			return &details, nil
+		}
+		return nil, err
+	}
+
+	c.store(ctx, key, fresh, c.ttls.Details)
+	return fresh, nil
+}
+
+// lookup decodes a fresh cache entry into dest, recording a hit or miss
+func (c *CachedPlacesProvider) lookup(ctx context.Context, call, key string, dest interface{}) bool {
+	if cacheBypassed(ctx) {
+		c.metrics.ObserveLookup(call, metrics.CacheBypass)
+		return false
+	}
+
+	entry, ok, err := c.backend.Get(ctx, key)
+	if err != nil {
+		c.logger.WarnContext(ctx, "Places cache read failed", "cache_key", key, "err", err)
+	}
+	if err != nil || !ok || entry.Expired() {
+		c.metrics.ObserveLookup(call, metrics.CacheMiss)
+		return false
+	}
+
+	if err := json.Unmarshal(entry.Value, dest); err != nil {
+		c.logger.WarnContext(ctx, "Discarding undecodable places cache entry", "cache_key", key, "err", err)
+		c.metrics.ObserveLookup(call, metrics.CacheMiss)
+		return false
+	}
+
+	c.metrics.ObserveLookup(call, metrics.CacheHit)
+	return true
+}
+
+// serveStale decodes an expired cache entry into dest when the upstream call
+// failed because the daily Places budget is spent or the circuit breaker is
+// open. Old results beat none, up to the Stale TTL.
+func (c *CachedPlacesProvider) serveStale(ctx context.Context, call string, err error, key string, dest interface{}) bool {
+	if !errors.Is(err, ErrPlacesBudgetExhausted) && !errors.Is(err, httpclient.ErrCircuitOpen) {
+		return false
+	}
+
+	entry, ok, getErr := c.backend.Get(ctx, key)
+	if getErr != nil || !ok {
+		return false
+	}
+	if c.ttls.Stale > 0 && time.Since(entry.ExpiresAt) > c.ttls.Stale {
+		return false
+	}
+	if err := json.Unmarshal(entry.Value, dest); err != nil {
+		return false
+	}
+
+	c.logger.InfoContext(ctx, "Serving stale places cache entry", "cache_key", key, "expired_at", entry.ExpiresAt)
+	c.metrics.ObserveLookup(call, metrics.CacheStale)
+	return true
+}
+
+// store writes a value to the cache, logging rather than failing on errors
+func (c *CachedPlacesProvider) store(ctx context.Context, key string, value interface{}, ttl time.Duration) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		c.logger.WarnContext(ctx, "Failed to encode places cache entry", "cache_key", key, "err", err)
+		return
+	}
+
+	if err := c.backend.Set(ctx, key, data, ttl); err != nil {
+		c.logger.WarnContext(ctx, "Places cache write failed", "cache_key", key, "err", err)
+	}
+}
+
+// geohashPrecision picks the geohash precision whose cells are small relative
+// to the search radius, so snapping the center barely shifts the search area
+func geohashPrecision(radius float64) int {
+	switch {
+	case radius >= 20000:
+		return 5 // ~4.9km cells
+	case radius >= 5000:
+		return 6 // ~1.2km cells
+	case radius >= 600:
+		return 7 // ~150m cells
+	default:
+		return 8 // ~38m cells
+	}
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultDiskCacheBytes is the disk cache size used when none is configured
+	defaultDiskCacheBytes = 512 << 20
+	// diskCacheSweepInterval is how often Set looks for entries past their grace period
+	diskCacheSweepInterval = 10 * time.Minute
+	// diskCacheTempPrefix starts the names of files that are still being written
+	diskCacheTempPrefix = "tmp-"
+)
+
+// DiskCache is a CacheBackend that stores each entry in its own file, for
+// values too large to keep in memory such as photos. An entry's expiry time
+// is stored as the file's modification time. It is local to one server instance.
+//
+// The cache holds at most maxBytes, evicting the least recently used entries
+// when full, and deletes entries once they are more than grace past their expiry.
+type DiskCache struct {
+	dir      string
+	maxBytes int64
+	grace    time.Duration
+
+	mu        sync.Mutex
+	size      int64
+	order     *list.List // Front is most recently used
+	entries   map[string]*list.Element
+	lastSweep time.Time
+}
+
+// diskCacheItem is the value stored in each list element
+type diskCacheItem struct {
+	name      string
+	size      int64
+	expiresAt time.Time
+}
+
+// NewDiskCache creates a disk cache in dir holding at most maxBytes, creating
+// the directory if needed. Entries already in dir are kept, oldest first in
+// line for eviction.
+func NewDiskCache(dir string, maxBytes int64, grace time.Duration) (*DiskCache, error) {
+	if maxBytes <= 0 {
+		maxBytes = defaultDiskCacheBytes
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	c := &DiskCache{
+		dir:       dir,
+		maxBytes:  maxBytes,
+		grace:     grace,
+		order:     list.New(),
+		entries:   make(map[string]*list.Element),
+		lastSweep: time.Now(),
+	}
+	if err := c.load(); err != nil {
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+	return c, nil
+}
+
+// load indexes the entries already on disk and removes abandoned temporary files
+func (c *DiskCache) load() error {
+	dirEntries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+
+	var items []*diskCacheItem
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.Type().IsRegular() {
+			continue
+		}
+		if strings.HasPrefix(dirEntry.Name(), diskCacheTempPrefix) {
+			os.Remove(filepath.Join(c.dir, dirEntry.Name()))
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		items = append(items, &diskCacheItem{name: dirEntry.Name(), size: info.Size(), expiresAt: info.ModTime()})
+	}
+
+	// Entries expiring soonest were most likely stored longest ago
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].expiresAt.Before(items[j].expiresAt)
+	})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, item := range items {
+		c.entries[item.name] = c.order.PushFront(item)
+		c.size += item.size
+	}
+	c.evict()
+
+	return nil
+}
+
+// Get returns the entry for key, including expired entries that have not been deleted yet
+func (c *DiskCache) Get(ctx context.Context, key string) (CacheEntry, bool, error) {
+	name := c.name(key)
+	path := filepath.Join(c.dir, name)
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		c.forget(name)
+		return CacheEntry{}, false, nil
+	}
+	if err != nil {
+		return CacheEntry{}, false, err
+	}
+
+	value, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		c.forget(name)
+		return CacheEntry{}, false, nil
+	}
+	if err != nil {
+		return CacheEntry{}, false, err
+	}
+
+	c.mu.Lock()
+	if elem, ok := c.entries[name]; ok {
+		c.order.MoveToFront(elem)
+	}
+	c.mu.Unlock()
+
+	return CacheEntry{Value: value, ExpiresAt: info.ModTime()}, true, nil
+}
+
+// Set stores value under key, evicting the least recently used entries when
+// full. The file is written under a temporary name and renamed, so readers
+// never see a partial entry.
+func (c *DiskCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	tmp, err := os.CreateTemp(c.dir, diskCacheTempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	name := c.name(key)
+	expiresAt := time.Now().Add(ttl)
+	if err := os.Chtimes(tmp.Name(), expiresAt, expiresAt); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(c.dir, name)); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[name]; ok {
+		item := elem.Value.(*diskCacheItem)
+		c.size += int64(len(value)) - item.size
+		item.size, item.expiresAt = int64(len(value)), expiresAt
+		c.order.MoveToFront(elem)
+	} else {
+		c.entries[name] = c.order.PushFront(&diskCacheItem{name: name, size: int64(len(value)), expiresAt: expiresAt})
+		c.size += int64(len(value))
+	}
+
+	if time.Since(c.lastSweep) >= diskCacheSweepInterval {
+		c.sweep()
+	}
+	c.evict()
+
+	return nil
+}
+
+// evict deletes least recently used entries until the cache fits in maxBytes.
+// The caller must hold c.mu.
+func (c *DiskCache) evict() {
+	for c.size > c.maxBytes && c.order.Len() > 0 {
+		c.remove(c.order.Back())
+	}
+}
+
+// sweep deletes entries more than grace past their expiry. The caller must hold c.mu.
+func (c *DiskCache) sweep() {
+	cutoff := time.Now().Add(-c.grace)
+	for elem := c.order.Back(); elem != nil; {
+		prev := elem.Prev()
+		if elem.Value.(*diskCacheItem).expiresAt.Before(cutoff) {
+			c.remove(elem)
+		}
+		elem = prev
+	}
+	c.lastSweep = time.Now()
+}
+
+// remove deletes an entry's file and drops it from the index. The caller must hold c.mu.
+func (c *DiskCache) remove(elem *list.Element) {
+	item := elem.Value.(*diskCacheItem)
+	os.Remove(filepath.Join(c.dir, item.name))
+	c.order.Remove(elem)
+	delete(c.entries, item.name)
+	c.size -= item.size
+}
+
+// forget drops an entry whose file has disappeared from the index
+func (c *DiskCache) forget(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[name]; ok {
+		item := elem.Value.(*diskCacheItem)
+		c.order.Remove(elem)
+		delete(c.entries, name)
+		c.size -= item.size
+	}
+}
+
+// name returns the file name holding key. Keys are hashed so any key is a
+// safe file name.
+func (c *DiskCache) name(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
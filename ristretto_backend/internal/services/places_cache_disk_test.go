@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// cached reports whether key has an entry in c
+func cached(t *testing.T, c *DiskCache, key string) bool {
+	t.Helper()
+	_, ok, err := c.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("get %s: %v", key, err)
+	}
+	return ok
+}
+
+func TestDiskCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c, err := NewDiskCache(t.TempDir(), 30, time.Hour)
+	if err != nil {
+		t.Fatalf("create cache: %v", err)
+	}
+	ctx := context.Background()
+	value := make([]byte, 10)
+
+	for _, key := range []string{"a", "b", "c"} {
+		if err := c.Set(ctx, key, value, time.Hour); err != nil {
+			t.Fatalf("set %s: %v", key, err)
+		}
+	}
+
+	// Reading a makes b the least recently used entry
+	cached(t, c, "a")
+	if err := c.Set(ctx, "d", value, time.Hour); err != nil {
+		t.Fatalf("set d: %v", err)
+	}
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true, "d": true} {
+		if got := cached(t, c, key); got != want {
+			t.Errorf("%s cached = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestDiskCacheDeletesEntriesPastGrace(t *testing.T) {
+	c, err := NewDiskCache(t.TempDir(), 1<<20, time.Hour)
+	if err != nil {
+		t.Fatalf("create cache: %v", err)
+	}
+	ctx := context.Background()
+
+	c.Set(ctx, "long-expired", []byte("x"), -2*time.Hour)
+	c.Set(ctx, "recently-expired", []byte("x"), -time.Minute)
+
+	// The next write after the sweep interval deletes entries past their grace
+	c.lastSweep = time.Now().Add(-diskCacheSweepInterval)
+	c.Set(ctx, "fresh", []byte("x"), time.Hour)
+
+	for key, want := range map[string]bool{"long-expired": false, "recently-expired": true, "fresh": true} {
+		if got := cached(t, c, key); got != want {
+			t.Errorf("%s cached = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestDiskCacheIndexesExistingEntries(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	first, err := NewDiskCache(dir, 1<<20, time.Hour)
+	if err != nil {
+		t.Fatalf("create cache: %v", err)
+	}
+	first.Set(ctx, "old", make([]byte, 10), time.Hour)
+	first.Set(ctx, "new", make([]byte, 10), 2*time.Hour)
+	if err := os.WriteFile(filepath.Join(dir, diskCacheTempPrefix+"abandoned"), []byte("x"), 0o600); err != nil {
+		t.Fatalf("write temp file: %v", err)
+	}
+
+	// A restart with a smaller cap evicts the entry expiring soonest
+	second, err := NewDiskCache(dir, 15, time.Hour)
+	if err != nil {
+		t.Fatalf("reopen cache: %v", err)
+	}
+	if cached(t, second, "old") || !cached(t, second, "new") {
+		t.Error("reopened cache kept the wrong entry")
+	}
+	if _, err := os.Stat(filepath.Join(dir, diskCacheTempPrefix+"abandoned")); !os.IsNotExist(err) {
+		t.Error("abandoned temporary file was not removed")
+	}
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/db"
+)
+
+// PostgresCache is a CacheBackend stored in the places_cache table, shared by
+// every server instance using the same database
+type PostgresCache struct {
+	db *db.DB
+}
+
+// NewPostgresCache creates a Postgres-backed cache
+func NewPostgresCache(db *db.DB) *PostgresCache {
+	return &PostgresCache{
+		db: db,
+	}
+}
+
+// Get returns the entry for key, including expired entries
+func (c *PostgresCache) Get(ctx context.Context, key string) (CacheEntry, bool, error) {
+	value, expiresAt, err := c.db.GetCacheEntry(ctx, key)
+	if err == sql.ErrNoRows {
+		return CacheEntry{}, false, nil
+	}
+	if err != nil {
+		return CacheEntry{}, false, err
+	}
+
+	return CacheEntry{Value: value, ExpiresAt: expiresAt}, true, nil
+}
+
+// Set stores value under key
+func (c *PostgresCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.db.SetCacheEntry(ctx, key, value, time.Now().Add(ttl))
+}
+
+// CachePruneWorker periodically deletes places_cache rows that expired long
+// enough ago that they can no longer be served stale
+type CachePruneWorker struct {
+	db       *db.DB
+	interval time.Duration
+	grace    time.Duration
+	logger   *slog.Logger
+}
+
+// NewCachePruneWorker creates a worker that every interval deletes entries
+// more than grace past their expiry
+func NewCachePruneWorker(db *db.DB, interval, grace time.Duration, logger *slog.Logger) *CachePruneWorker {
+	return &CachePruneWorker{
+		db:       db,
+		interval: interval,
+		grace:    grace,
+		logger:   logger,
+	}
+}
+
+// Run prunes the cache on every tick until the context is cancelled
+func (w *CachePruneWorker) Run(ctx context.Context) {
+	w.logger.Info("Places cache prune worker started", "interval", w.interval, "grace", w.grace)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if pruned, err := w.PruneOnce(ctx); err != nil {
+			w.logger.Error("Places cache prune failed", "err", err)
+		} else if pruned > 0 {
+			w.logger.Info("Pruned expired places cache entries", "count", pruned)
+		}
+
+		select {
+		case <-ctx.Done():
+			w.logger.Info("Places cache prune worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PruneOnce deletes entries more than grace past their expiry and returns how many were deleted
+func (w *CachePruneWorker) PruneOnce(ctx context.Context) (int64, error) {
+	return w.db.DeleteExpiredCacheEntries(ctx, time.Now().Add(-w.grace))
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/metrics"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
+)
+
+func TestCachedPlacesProviderRecordsLookups(t *testing.T) {
+	registry := metrics.NewRegistry()
+	provider := NewCachedPlacesProvider(gridProvider(34, -118, 0.01, 0.002), NewMemoryCache(100),
+		CacheTTLs{Nearby: time.Hour, Details: time.Hour}, metrics.NewPlacesCacheMetrics(registry), discardLogger)
+	ctx := context.Background()
+
+	for _, ctx := range []context.Context{ctx, ctx, WithCacheBypass(ctx)} {
+		if _, err := provider.SearchNearby(ctx, 34, -118, 500, 20); err != nil {
+			t.Fatalf("search nearby: %v", err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	registry.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+
+	for _, want := range []string{
+		`places_cache_requests_total{call="nearby",result="miss"} 1`,
+		`places_cache_requests_total{call="nearby",result="hit"} 1`,
+		`places_cache_requests_total{call="nearby",result="bypass"} 1`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestCachedPlacesProviderReturnsCallersCircle(t *testing.T) {
+	places := gridProvider(34, -118, 0.02, 0.001)
+	provider := NewCachedPlacesProvider(places, NewMemoryCache(100), CacheTTLs{Nearby: time.Hour}, nil, discardLogger)
+	ctx := context.Background()
+
+	// Callers away from the center of their geohash cell, searching a circle
+	// with places right at its edge
+	for _, center := range [][2]float64{{34.00049, -118.00061}, {33.99921, -117.99937}, {34.0004, -118.0005}} {
+		want, err := places.SearchNearby(ctx, center[0], center[1], 450, 1000)
+		if err != nil {
+			t.Fatalf("search fixture: %v", err)
+		}
+		got, err := provider.SearchNearby(ctx, center[0], center[1], 450, 1000)
+		if err != nil {
+			t.Fatalf("search cache: %v", err)
+		}
+
+		gotIDs := make(map[string]bool)
+		for _, place := range got {
+			gotIDs[place.PlaceID] = true
+		}
+		for _, place := range want {
+			if !gotIDs[place.PlaceID] {
+				t.Errorf("search from %v missed %s", center, place.PlaceID)
+			}
+		}
+		if len(got) != len(want) {
+			t.Errorf("search from %v returned %d places, want %d", center, len(got), len(want))
+		}
+	}
+}
+
+// refusingProvider fails every call as if the daily budget were spent
+type refusingProvider struct {
+	PlacesProvider
+}
+
+// GetPlaceDetails implements PlacesProvider
+func (refusingProvider) GetPlaceDetails(ctx context.Context, placeID string) (*models.PlaceDetails, error) {
+	return nil, ErrPlacesBudgetExhausted
+}
+
+func TestCachedPlacesProviderServesStaleWithinLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		expiredAt time.Duration
+		wantStale bool
+	}{
+		{name: "recently expired", expiredAt: time.Hour, wantStale: true},
+		{name: "expired too long ago", expiredAt: 48 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := NewMemoryCache(10)
+			ctx := context.Background()
+			if err := backend.Set(ctx, "details:cafe", []byte(`{"id":"cafe"}`), -tt.expiredAt); err != nil {
+				t.Fatalf("seed cache: %v", err)
+			}
+			provider := NewCachedPlacesProvider(refusingProvider{}, backend,
+				CacheTTLs{Details: time.Hour, Stale: 24 * time.Hour}, nil, discardLogger)
+
+			details, err := provider.GetPlaceDetails(ctx, "cafe")
+			if tt.wantStale && (err != nil || details.PlaceID != "cafe") {
+				t.Fatalf("got %v, %v; want the stale entry", details, err)
+			}
+			if !tt.wantStale && !errors.Is(err, ErrPlacesBudgetExhausted) {
+				t.Fatalf("got %v, %v; want ErrPlacesBudgetExhausted", details, err)
+			}
+		})
+	}
+}
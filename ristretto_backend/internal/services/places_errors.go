@@ -0,0 +1,50 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// PlacesAPIError is returned when the places provider answers with an error
+// status. Handlers map it to an HTTP status for the client.
+type PlacesAPIError struct {
+	StatusCode int    // HTTP status returned by the provider
+	Status     string // Google status such as "NOT_FOUND" or "INVALID_ARGUMENT"
+	Message    string
+}
+
+// Error implements error
+func (e *PlacesAPIError) Error() string {
+	return fmt.Sprintf("Google Places API error %d %s: %s", e.StatusCode, e.Status, e.Message)
+}
+
+// NotFound reports whether the provider did not recognize the place
+func (e *PlacesAPIError) NotFound() bool {
+	return e.StatusCode == http.StatusNotFound || e.Status == "NOT_FOUND"
+}
+
+// InvalidPlace reports whether the provider rejected the place ID itself,
+// as unknown or malformed, so asking again can never succeed
+func (e *PlacesAPIError) InvalidPlace() bool {
+	return e.NotFound() || e.StatusCode == http.StatusBadRequest || e.Status == "INVALID_ARGUMENT"
+}
+
+// newPlacesAPIError builds a PlacesAPIError from an error response body of the
+// form {"error": {"code": 404, "message": "...", "status": "NOT_FOUND"}}
+func newPlacesAPIError(statusCode int, body []byte) *PlacesAPIError {
+	apiErr := &PlacesAPIError{StatusCode: statusCode, Message: string(body)}
+
+	var errorResp struct {
+		Error struct {
+			Message string `json:"message"`
+			Status  string `json:"status"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errorResp); err == nil && errorResp.Error.Message != "" {
+		apiErr.Status = errorResp.Error.Status
+		apiErr.Message = errorResp.Error.Message
+	}
+
+	return apiErr
+}
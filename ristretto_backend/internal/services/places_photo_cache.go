@@ -0,0 +1,86 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/httpclient"
+)
+
+// PhotoCache serves photos from a cache backend, fetching and storing them on
+// a miss. A nil backend disables caching.
+type PhotoCache struct {
+	fetcher PhotoFetcher
+	backend CacheBackend
+	ttl     time.Duration
+	logger  *slog.Logger
+}
+
+// NewPhotoCache creates a PhotoCache in front of fetcher
+func NewPhotoCache(fetcher PhotoFetcher, backend CacheBackend, ttl time.Duration, logger *slog.Logger) *PhotoCache {
+	return &PhotoCache{
+		fetcher: fetcher,
+		backend: backend,
+		ttl:     ttl,
+		logger:  logger,
+	}
+}
+
+// GetPhoto returns a photo at one of the PhotoSizes presets. An expired
+// entry is served when the upstream call fails because the daily Places
+// budget is spent or the circuit breaker is open.
+func (c *PhotoCache) GetPhoto(ctx context.Context, photoName, size string) (*PhotoMedia, error) {
+	key := "photo:" + size + ":" + photoName
+
+	var stale *PhotoMedia
+	if c.backend != nil {
+		entry, ok, err := c.backend.Get(ctx, key)
+		if err != nil {
+			// Fall through to the provider rather than failing
+			c.logger.WarnContext(ctx, "Photo cache read failed", "cache_key", key, "err", err)
+		} else if ok {
+			if photo, ok := decodePhoto(entry.Value); ok {
+				if !entry.Expired() {
+					return photo, nil
+				}
+				stale = photo
+			}
+		}
+	}
+
+	photo, err := c.fetcher.FetchPhoto(ctx, photoName, PhotoSizes[size])
+	if err != nil {
+		if stale != nil && (errors.Is(err, ErrPlacesBudgetExhausted) || errors.Is(err, httpclient.ErrCircuitOpen)) {
+			c.logger.InfoContext(ctx, "Serving stale photo cache entry", "cache_key", key)
+			return stale, nil
+		}
+		return nil, err
+	}
+
+	if c.backend != nil {
+		if err := c.backend.Set(ctx, key, encodePhoto(photo), c.ttl); err != nil {
+			c.logger.WarnContext(ctx, "Photo cache write failed", "cache_key", key, "err", err)
+		}
+	}
+	return photo, nil
+}
+
+// encodePhoto serializes a photo as its content type, a newline and the image data
+func encodePhoto(photo *PhotoMedia) []byte {
+	value := make([]byte, 0, len(photo.ContentType)+1+len(photo.Data))
+	value = append(value, photo.ContentType...)
+	value = append(value, '\n')
+	return append(value, photo.Data...)
+}
+
+// decodePhoto parses a value written by encodePhoto
+func decodePhoto(value []byte) (*PhotoMedia, bool) {
+	contentType, data, ok := bytes.Cut(value, []byte{'\n'})
+	if !ok || len(contentType) == 0 {
+		return nil, false
+	}
+	return &PhotoMedia{ContentType: string(contentType), Data: data}, true
+}
@@ -0,0 +1,34 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// photoSignatureBytes is how much of the HMAC is kept in photo URLs
+const photoSignatureBytes = 16
+
+// PhotoSigner signs photo proxy URLs, so the proxy only fetches photos the
+// API handed out and can't be used to spend the Places budget on arbitrary
+// photo names
+type PhotoSigner struct {
+	key []byte
+}
+
+// NewPhotoSigner creates a PhotoSigner using key as the HMAC key
+func NewPhotoSigner(key []byte) *PhotoSigner {
+	return &PhotoSigner{key: key}
+}
+
+// Sign returns the signature of a photo name at a size preset
+func (s *PhotoSigner) Sign(photoName, size string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(photoName + "\n" + size))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:photoSignatureBytes])
+}
+
+// Verify reports whether signature is valid for a photo name at a size preset
+func (s *PhotoSigner) Verify(photoName, size, signature string) bool {
+	return hmac.Equal([]byte(signature), []byte(s.Sign(photoName, size)))
+}
@@ -1,9 +1,17 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"math"
+	"net/http"
 	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/metrics"
 )
 
 // PhotoSize specifies the desired size for a photo
@@ -12,42 +20,88 @@ type PhotoSize struct {
 	MaxHeightPx int
 }
 
-// GetPhotoURL generates a URL to fetch a photo from the Google Places API
-func (s *PlacesService) GetPhotoURL(photoName string, size PhotoSize) string {
-	if photoName == "" {
-		log.Println("WARNING: Empty photo name provided")
-		return ""
+// Fit returns the dimensions of a width by height image scaled down to fit
+// the size, keeping its aspect ratio. Images are never scaled up.
+func (s PhotoSize) Fit(width, height int) (int, int) {
+	if width <= 0 || height <= 0 {
+		return 0, 0
 	}
 
-	// Base URL for the Places Photos API
-	baseURL := "https://places.googleapis.com/v1/"
+	scale := 1.0
+	if s.MaxWidthPx > 0 {
+		scale = min(scale, float64(s.MaxWidthPx)/float64(width))
+	}
+	if s.MaxHeightPx > 0 {
+		scale = min(scale, float64(s.MaxHeightPx)/float64(height))
+	}
+	return max(int(math.Round(float64(width)*scale)), 1), max(int(math.Round(float64(height)*scale)), 1)
+}
 
-	// Create URL with parameters
-	photoURL := fmt.Sprintf("%s%s/media", baseURL, photoName)
+// Photo size presets. Clients can only request these, so the photo cache
+// holds a bounded number of variants of each photo.
+const (
+	PhotoSizeThumbnail = "thumbnail"
+	PhotoSizeSmall     = "small"
+	PhotoSizeMedium    = "medium"
+	PhotoSizeLarge     = "large"
+)
 
-	// Add parameters
-	params := url.Values{}
-	params.Add("key", s.APIKey)
+// PhotoSizes maps each preset to its maximum dimensions
+var PhotoSizes = map[string]PhotoSize{
+	PhotoSizeThumbnail: {MaxWidthPx: 200, MaxHeightPx: 200},
+	PhotoSizeSmall:     {MaxWidthPx: 400, MaxHeightPx: 300},
+	PhotoSizeMedium:    {MaxWidthPx: 800, MaxHeightPx: 600},
+	PhotoSizeLarge:     {MaxWidthPx: 1600, MaxHeightPx: 1200},
+}
 
-	// Add size parameters
-	if size.MaxWidthPx > 0 {
-		params.Add("maxWidthPx", fmt.Sprintf("%d", size.MaxWidthPx))
+// maxPhotoBytes bounds the size of a photo read from the Places API
+const maxPhotoBytes = 10 << 20
+
+// photoNamePattern matches Google Places photo resource names, e.g.
+// "places/ChIJ.../photos/AUc7..."
+var photoNamePattern = regexp.MustCompile(`^places/[A-Za-z0-9_-]+/photos/[A-Za-z0-9_-]+$`)
+
+// ValidPhotoName reports whether name is a Google Places photo resource name
+func ValidPhotoName(name string) bool {
+	return photoNamePattern.MatchString(name)
+}
+
+// PhotoMedia is a photo's image data
+type PhotoMedia struct {
+	ContentType string
+	Data        []byte
+}
+
+// ErrPhotosUnsupported is returned when the places provider cannot fetch photos
+var ErrPhotosUnsupported = errors.New("places provider does not serve photos")
+
+// PhotoFetcher is implemented by places providers that can fetch photo
+// images server-side
+type PhotoFetcher interface {
+	FetchPhoto(ctx context.Context, photoName string, size PhotoSize) (*PhotoMedia, error)
+}
+
+// GetPhotoURL generates a signed URL to fetch a photo through our photo
+// proxy, so the API key never reaches clients. size must be one of the
+// PhotoSizes presets.
+func (s *PlacesService) GetPhotoURL(photoName string, size string) string {
+	if !ValidPhotoName(photoName) {
+		s.logger.Warn("Invalid photo name provided", "photo_name", photoName)
+		return ""
 	}
-	if size.MaxHeightPx > 0 {
-		params.Add("maxHeightPx", fmt.Sprintf("%d", size.MaxHeightPx))
+	if _, ok := PhotoSizes[size]; !ok {
+		size = PhotoSizeSmall
 	}
 
-	// If neither width nor height specified, use a default
-	if size.MaxWidthPx <= 0 && size.MaxHeightPx <= 0 {
-		params.Add("maxWidthPx", "400")
+	query := url.Values{"size": {size}}
+	if s.photoSigner != nil {
+		query.Set("sig", s.photoSigner.Sign(photoName, size))
 	}
-
-	// Combine URL and parameters
-	return photoURL + "?" + params.Encode()
+	return s.photoBaseURL + "/photos/" + photoName + "?" + query.Encode()
 }
 
-// TransformPhotoURLs converts photo resource names to actual URLs
-func (s *PlacesService) TransformPhotoURLs(photoNames []string, size PhotoSize) []string {
+// TransformPhotoURLs converts photo resource names to photo proxy URLs
+func (s *PlacesService) TransformPhotoURLs(photoNames []string, size string) []string {
 	if len(photoNames) == 0 {
 		return nil
 	}
@@ -61,3 +115,55 @@ func (s *PlacesService) TransformPhotoURLs(photoNames []string, size PhotoSize)
 
 	return photoURLs
 }
+
+// FetchPhoto downloads a photo from the Google Places API. Each fetch is a
+// billable call, so it is counted against the daily budget.
+func (s *PlacesService) FetchPhoto(ctx context.Context, photoName string, size PhotoSize) (*PhotoMedia, error) {
+	ctx = metrics.WithOperation(ctx, metrics.OpPhoto)
+	if !ValidPhotoName(photoName) {
+		return nil, fmt.Errorf("invalid photo name: %q", photoName)
+	}
+
+	params := url.Values{}
+	if size.MaxWidthPx > 0 {
+		params.Set("maxWidthPx", fmt.Sprintf("%d", size.MaxWidthPx))
+	}
+	if size.MaxHeightPx > 0 {
+		params.Set("maxHeightPx", fmt.Sprintf("%d", size.MaxHeightPx))
+	}
+	endpoint := fmt.Sprintf("%s/v1/%s/media?%s", s.baseURL, photoName, params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Goog-Api-Key", s.APIKey)
+
+	// Google redirects to the image, which the client follows
+	resp, err := s.send(req)
+	if err != nil {
+		s.logger.WarnContext(ctx, "Failed to connect to Google Places API", "err", err)
+		return nil, fmt.Errorf("failed to fetch photo: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, maxPhotoBytes))
+		return nil, s.apiError(ctx, resp.StatusCode, bodyBytes)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, fmt.Errorf("unexpected photo content type: %q", contentType)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPhotoBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read photo: %w", err)
+	}
+	if len(data) > maxPhotoBytes {
+		return nil, fmt.Errorf("photo exceeds %d bytes", maxPhotoBytes)
+	}
+
+	return &PhotoMedia{ContentType: contentType, Data: data}, nil
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/config"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/db"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/metrics"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
+)
+
+// PlacesProvider is a source of coffee shop place data
+type PlacesProvider interface {
+	// SearchNearby searches for coffee shops within radius meters of a location
+	SearchNearby(ctx context.Context, latitude, longitude, radius float64, maxResults int) ([]models.Place, error)
+	// GetPlaceDetails fetches detailed information about a place
+	GetPlaceDetails(ctx context.Context, placeID string) (*models.PlaceDetails, error)
+	// SearchText searches for coffee shops matching a free-text query, optionally biased towards an area
+	SearchText(ctx context.Context, query string, bias *models.Circle, maxResults int) ([]models.Place, error)
+	// Autocomplete returns coffee shop suggestions for partially typed input
+	Autocomplete(ctx context.Context, input string, bias *models.Circle, sessionToken string) ([]models.PlaceSuggestion, error)
+	// GetPhotoURL generates a URL clients can use to fetch a photo at a PhotoSizes preset
+	GetPhotoURL(photoName string, size string) string
+	// TransformPhotoURLs converts photo resource names to URLs
+	TransformPhotoURLs(photoNames []string, size string) []string
+}
+
+// Pinger is implemented by places providers that can check they are
+// reachable without making a billable call
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Supported places providers
+const (
+	ProviderGoogle  = "google"
+	ProviderFixture = "fixture"
+)
+
+// Ensure the providers implement PlacesProvider
+var (
+	_ PlacesProvider = (*PlacesService)(nil)
+	_ PlacesProvider = (*FixturePlacesProvider)(nil)
+	_ PlacesProvider = (*CachedPlacesProvider)(nil)
+)
+
+// NewPlacesProvider creates the places provider selected in the configuration
+// The daily call budget is counted in database, shared by every instance.
+func NewPlacesProvider(cfg *config.Config, database *db.DB, placesMetrics *metrics.PlacesMetrics, logger *slog.Logger) (PlacesProvider, error) {
+	switch cfg.Places.Provider {
+	case ProviderGoogle, "":
+		location, err := time.LoadLocation(cfg.Places.BudgetTimezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid places budget timezone: %w", err)
+		}
+		budget := NewPlacesBudget(cfg.Places.DailyBudget, location, database)
+		return NewPlacesService(PlacesServiceOptions{
+			APIKey:       cfg.Google.PlacesAPIKey,
+			BaseURL:      cfg.Places.BaseURL,
+			PhotoBaseURL: cfg.Photos.BaseURL,
+			PhotoSigner:  NewPhotoSigner([]byte(cfg.Photos.SigningKey)),
+			Client:       NewPlacesClient(cfg.Places, budget, placesMetrics, logger),
+			Timeout:      cfg.Places.Timeout,
+			Logger:       logger,
+		}), nil
+	case ProviderFixture:
+		provider, err := NewFixturePlacesProvider(cfg.Places.FixturePath)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Using fixture places provider", "path", cfg.Places.FixturePath, "places", len(provider.places))
+		return provider, nil
+	default:
+		return nil, fmt.Errorf("unknown places provider: %s", cfg.Places.Provider)
+	}
+}
+
+// Supported places cache backends
+const (
+	CacheBackendMemory   = "memory"
+	CacheBackendPostgres = "postgres"
+	CacheBackendNone     = "none"
+)
+
+// NewCacheBackend creates the places cache backend selected in the
+// configuration. It returns nil when caching is disabled.
+func NewCacheBackend(cfg *config.Config, database *db.DB) (CacheBackend, error) {
+	switch cfg.Cache.Backend {
+	case CacheBackendMemory, "":
+		return NewMemoryCache(cfg.Cache.MemoryEntries), nil
+	case CacheBackendPostgres:
+		return NewPostgresCache(database), nil
+	case CacheBackendNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown places cache backend: %s", cfg.Cache.Backend)
+	}
+}
+
+// Supported photo cache backends
+const (
+	PhotoCacheDisk     = "disk"
+	PhotoCachePostgres = "postgres"
+	PhotoCacheNone     = "none"
+)
+
+// NewPhotoCacheBackend creates the photo cache backend selected in the
+// configuration. It returns nil when caching is disabled.
+func NewPhotoCacheBackend(cfg *config.Config, database *db.DB) (CacheBackend, error) {
+	switch cfg.Photos.CacheBackend {
+	case PhotoCacheDisk, "":
+		return NewDiskCache(cfg.Photos.CacheDir, int64(cfg.Photos.CacheMaxMB)<<20, cfg.Cache.StaleFor)
+	case PhotoCachePostgres:
+		return NewPostgresCache(database), nil
+	case PhotoCacheNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown photo cache backend: %s", cfg.Photos.CacheBackend)
+	}
+}
@@ -1,33 +1,108 @@
 package services
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/config"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/httpclient"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/metrics"
 	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
 )
 
 // PlacesService interacts with the Google Places API
 type PlacesService struct {
-	APIKey string
+	APIKey       string
+	baseURL      string
+	photoBaseURL string
+	photoSigner  *PhotoSigner
+	client       *http.Client
+	timeout      time.Duration
+	logger       *slog.Logger
 }
 
+// PlacesServiceOptions configure a PlacesService
+type PlacesServiceOptions struct {
+	APIKey       string
+	BaseURL      string        // API endpoint; empty uses DefaultPlacesBaseURL
+	PhotoBaseURL string        // Prefix of photo proxy URLs; empty makes them relative
+	PhotoSigner  *PhotoSigner  // Signs photo proxy URLs; nil leaves them unsigned
+	Client       *http.Client  // Shared client for all calls; nil uses a plain client
+	Timeout      time.Duration // Deadline for each call, including retries; 0 leaves it to the caller
+	Logger       *slog.Logger
+}
+
+// DefaultPlacesBaseURL is the Google Places API endpoint
+const DefaultPlacesBaseURL = "https://places.googleapis.com"
+
 // NewPlacesService creates a new PlacesService
-func NewPlacesService(apiKey string) *PlacesService {
+func NewPlacesService(opts PlacesServiceOptions) *PlacesService {
+	baseURL := strings.TrimSuffix(opts.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = DefaultPlacesBaseURL
+	}
+	client := opts.Client
+	if client == nil {
+		client = &http.Client{}
+	}
+
 	return &PlacesService{
-		APIKey: apiKey,
+		APIKey:       opts.APIKey,
+		baseURL:      baseURL,
+		photoBaseURL: strings.TrimSuffix(opts.PhotoBaseURL, "/"),
+		photoSigner:  opts.PhotoSigner,
+		client:       client,
+		timeout:      opts.Timeout,
+		logger:       opts.Logger,
 	}
 }
 
+// NewPlacesClient creates the HTTP client shared by all Google Places calls.
+// Failed calls are retried with backoff behind a circuit breaker. Every
+// attempt spends budget, which may be nil, and is recorded in placesMetrics
+// if it is set.
+func NewPlacesClient(cfg config.PlacesConfig, budget *PlacesBudget, placesMetrics *metrics.PlacesMetrics, logger *slog.Logger) *http.Client {
+	var transport http.RoundTripper = http.DefaultTransport
+	if placesMetrics != nil {
+		transport = metrics.InstrumentTransport(transport, placesMetrics)
+	}
+	transport = newBudgetTransport(transport, budget, placesMetrics, logger)
+	breaker := httpclient.NewBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown, logger.With("upstream", "google_places"))
+	transport = httpclient.NewBreakerTransport(transport, breaker)
+	transport = httpclient.NewRetryTransport(transport, httpclient.RetryPolicy{
+		MaxRetries: cfg.MaxRetries,
+		BaseDelay:  cfg.RetryBaseDelay,
+		MaxDelay:   cfg.RetryMaxDelay,
+	}, logger)
+
+	return &http.Client{Transport: transport, CheckRedirect: stripAPIKeyOnRedirect}
+}
+
+// stripAPIKeyOnRedirect keeps the API key from following a redirect to
+// another host, such as photo media redirecting to the image CDN
+func stripAPIKeyOnRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if req.URL.Host != via[0].URL.Host {
+		req.Header.Del("X-Goog-Api-Key")
+	}
+	return nil
+}
+
 // SearchNearby searches for coffee shops near a location
-func (s *PlacesService) SearchNearby(latitude, longitude, radius float64, maxResults int) ([]models.Place, error) {
-	log.Printf("Making request to Google Places API with coords: %f, %f, radius: %f", latitude, longitude, radius)
+func (s *PlacesService) SearchNearby(ctx context.Context, latitude, longitude, radius float64, maxResults int) ([]models.Place, error) {
+	ctx = metrics.WithOperation(ctx, metrics.OpSearchNearby)
+	s.logger.DebugContext(ctx, "Searching Google Places nearby", "lat", latitude, "lng", longitude, "radius", radius)
 	apiKey := s.APIKey
-	log.Printf("Google Places API Key present: %v (length: %d)", apiKey != "", len(apiKey))
 
 	// Prepare request to Google Places API
 	requestBody := models.PlacesRequest{
@@ -47,51 +122,103 @@ func (s *PlacesService) SearchNearby(latitude, longitude, radius float64, maxRes
 	// Convert request to JSON
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		log.Printf("JSON marshal error: %v", err)
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Create HTTP request to Google Places API
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		ctx,
 		"POST",
-		"https://places.googleapis.com/v1/places:searchNearby",
-		strings.NewReader(string(jsonData)),
+		s.baseURL+"/v1/places:searchNearby",
+		bytes.NewReader(jsonData),
 	)
 	if err != nil {
-		log.Printf("Request creation error: %v", err)
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set headers
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-Goog-Api-Key", apiKey)
-	req.Header.Set("X-Goog-FieldMask", "places.displayName,places.id,places.location")
+	req.Header.Set("X-Goog-FieldMask", "places.displayName,places.id,places.location,places.rating,places.priceLevel,places.currentOpeningHours.openNow")
 
 	// Send request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := s.send(req)
 	if err != nil {
-		log.Printf("ERROR: Failed to connect to Google Places API: %v", err)
+		s.logger.WarnContext(ctx, "Failed to connect to Google Places API", "err", err)
 		return nil, fmt.Errorf("failed to fetch coffee shops: %w", err)
 	}
 	defer resp.Body.Close()
 
-	log.Printf("Google Places API response received with status: %d", resp.StatusCode)
 	// Check response status
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		log.Printf("Google API error: %s, Status: %d", string(bodyBytes), resp.StatusCode)
-		return nil, fmt.Errorf("Google Places API error: %s", string(bodyBytes))
+		return nil, s.apiError(ctx, resp.StatusCode, bodyBytes)
 	}
 
 	// Parse response
 	var placesResp models.PlacesResponse
 	if err := json.NewDecoder(resp.Body).Decode(&placesResp); err != nil {
-		log.Printf("Response parsing error: %v", err)
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	log.Printf("Successfully parsed Google Places API response with %d places", len(placesResp.Places))
+	s.logger.DebugContext(ctx, "Google Places nearby search completed", "count", len(placesResp.Places))
 
 	return placesResp.Places, nil
 }
+
+// send makes a call to the Google Places API. The call, including any
+// retries, must finish within the service timeout; the deadline is released
+// when the response body is closed.
+func (s *PlacesService) send(req *http.Request) (*http.Response, error) {
+	if s.timeout <= 0 {
+		return s.client.Do(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), s.timeout)
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody releases a request's deadline once its response body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and releases the deadline
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// apiError logs and returns an error response from the Google Places API.
+// Only the parsed status and message are logged, never the response body.
+func (s *PlacesService) apiError(ctx context.Context, statusCode int, body []byte) error {
+	err := newPlacesAPIError(statusCode, body)
+	s.logger.WarnContext(ctx, "Google Places API returned an error", "status", statusCode, "err", err)
+	return err
+}
+
+// Ping checks that the Google Places API is reachable. It sends an
+// unauthenticated HEAD request, so any HTTP response counts as reachable and
+// nothing is billed.
+func (s *PlacesService) Ping(ctx context.Context) error {
+	ctx = withUnbilled(metrics.WithOperation(ctx, metrics.OpPing))
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, s.baseURL+"/", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := s.send(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Google Places API: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
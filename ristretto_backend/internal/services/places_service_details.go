@@ -1,18 +1,21 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
+	"net/url"
 
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/metrics"
 	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
 )
 
 // GetPlaceDetails fetches detailed information about a place from the Google Places API
-func (s *PlacesService) GetPlaceDetails(placeID string) (*models.PlaceDetails, error) {
-	log.Printf("Fetching details for place ID: %s", placeID)
+func (s *PlacesService) GetPlaceDetails(ctx context.Context, placeID string) (*models.PlaceDetails, error) {
+	ctx = metrics.WithOperation(ctx, metrics.OpGetPlaceDetails)
+	s.logger.DebugContext(ctx, "Fetching Google Places details", "place_id", placeID)
 	apiKey := s.APIKey
 
 	if apiKey == "" {
@@ -20,46 +23,36 @@ func (s *PlacesService) GetPlaceDetails(placeID string) (*models.PlaceDetails, e
 	}
 
 	// Create HTTP request to Google Places API
-	url := fmt.Sprintf("https://places.googleapis.com/v1/places/%s", placeID)
-	req, err := http.NewRequest("GET", url, nil)
+	endpoint := fmt.Sprintf("%s/v1/places/%s", s.baseURL, url.PathEscape(placeID))
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
-		log.Printf("Request creation error: %v", err)
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set headers
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-Goog-Api-Key", apiKey)
-	req.Header.Set("X-Goog-FieldMask", "id,displayName,formattedAddress,location,googleMapsUri,websiteUri,internationalPhoneNumber,currentOpeningHours,photos")
+	req.Header.Set("X-Goog-FieldMask", "id,displayName,formattedAddress,location,googleMapsUri,websiteUri,internationalPhoneNumber,rating,priceLevel,currentOpeningHours,photos")
 
 	// Send request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := s.send(req)
 	if err != nil {
-		log.Printf("ERROR: Failed to connect to Google Places API: %v", err)
+		s.logger.WarnContext(ctx, "Failed to connect to Google Places API", "err", err)
 		return nil, fmt.Errorf("failed to fetch place details: %w", err)
 	}
 	defer resp.Body.Close()
 
-	log.Printf("Google Places API response received with status: %d", resp.StatusCode)
-
 	// Check response status
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		log.Printf("Google API error: %s, Status: %d", string(bodyBytes), resp.StatusCode)
-		return nil, fmt.Errorf("Google Places API error: %s", string(bodyBytes))
+		return nil, s.apiError(ctx, resp.StatusCode, bodyBytes)
 	}
 
-	// Read the full response body for debugging
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Printf("Error reading response body: %v", err)
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	// Log the response for debugging
-	log.Printf("Google Places API response: %s", string(bodyBytes))
-
 	// Initialize struct with default values
 	placeDetails := &models.PlaceDetails{
 		PlaceID: placeID,
@@ -74,7 +67,6 @@ func (s *PlacesService) GetPlaceDetails(placeID string) (*models.PlaceDetails, e
 
 	// Parse the response
 	if err := json.Unmarshal(bodyBytes, placeDetails); err != nil {
-		log.Printf("Response parsing error: %v", err)
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
@@ -87,7 +79,5 @@ func (s *PlacesService) GetPlaceDetails(placeID string) (*models.PlaceDetails, e
 		placeDetails.DisplayName.Text = "Unknown Place"
 	}
 
-	log.Printf("Successfully parsed Google Places API details response for: %s", placeDetails.DisplayName.Text)
-
 	return placeDetails, nil
 }
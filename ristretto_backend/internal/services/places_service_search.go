@@ -0,0 +1,113 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/metrics"
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/models"
+)
+
+// SearchText searches for coffee shops matching a free-text query such as
+// "Intelligentsia Silver Lake", optionally biased towards an area
+func (s *PlacesService) SearchText(ctx context.Context, query string, bias *models.Circle, maxResults int) ([]models.Place, error) {
+	ctx = metrics.WithOperation(ctx, metrics.OpSearchText)
+	s.logger.DebugContext(ctx, "Searching Google Places by text", "query", query)
+
+	requestBody := models.TextSearchRequest{
+		TextQuery:           query,
+		IncludedType:        "cafe",
+		StrictTypeFiltering: true,
+		PageSize:            maxResults,
+	}
+	if bias != nil {
+		requestBody.LocationBias = &models.LocationBias{Circle: *bias}
+	}
+
+	var placesResp models.PlacesResponse
+	err := s.postJSON(ctx, s.baseURL+"/v1/places:searchText", requestBody,
+		"places.displayName,places.id,places.location", &placesResp)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.DebugContext(ctx, "Google Places text search completed", "count", len(placesResp.Places))
+
+	return placesResp.Places, nil
+}
+
+// Autocomplete returns coffee shop suggestions for partially typed input.
+// Requests sharing a session token are billed as a single session by Google.
+func (s *PlacesService) Autocomplete(ctx context.Context, input string, bias *models.Circle, sessionToken string) ([]models.PlaceSuggestion, error) {
+	ctx = metrics.WithOperation(ctx, metrics.OpAutocomplete)
+	requestBody := models.AutocompleteRequest{
+		Input:                input,
+		IncludedPrimaryTypes: []string{"cafe", "coffee_shop"},
+		SessionToken:         sessionToken,
+	}
+	if bias != nil {
+		requestBody.LocationBias = &models.LocationBias{Circle: *bias}
+	}
+
+	var autocompleteResp models.AutocompleteResponse
+	err := s.postJSON(ctx, s.baseURL+"/v1/places:autocomplete", requestBody, "", &autocompleteResp)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]models.PlaceSuggestion, 0, len(autocompleteResp.Suggestions))
+	for _, suggestion := range autocompleteResp.Suggestions {
+		prediction := suggestion.PlacePrediction
+		if prediction == nil || prediction.PlaceID == "" {
+			continue
+		}
+		suggestions = append(suggestions, models.PlaceSuggestion{
+			PlaceID:       prediction.PlaceID,
+			Name:          prediction.StructuredFormat.MainText.Text,
+			SecondaryText: prediction.StructuredFormat.SecondaryText.Text,
+		})
+	}
+
+	return suggestions, nil
+}
+
+// postJSON sends a JSON POST request to the Google Places API and decodes the response into dest
+func (s *PlacesService) postJSON(ctx context.Context, url string, body interface{}, fieldMask string, dest interface{}) error {
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Goog-Api-Key", s.APIKey)
+	if fieldMask != "" {
+		req.Header.Set("X-Goog-FieldMask", fieldMask)
+	}
+
+	resp, err := s.send(req)
+	if err != nil {
+		s.logger.WarnContext(ctx, "Failed to connect to Google Places API", "err", err)
+		return fmt.Errorf("failed to reach Google Places API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return s.apiError(ctx, resp.StatusCode, bodyBytes)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(dest); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RobertGarabetian/ristretto/ristretto_backend/internal/config"
+)
+
+// discardLogger drops log output in tests
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// newTestPlacesService creates a PlacesService calling server with retries,
+// a per-call timeout and an optional budget
+func newTestPlacesService(server *httptest.Server, timeout time.Duration, budget *PlacesBudget) *PlacesService {
+	client := NewPlacesClient(config.PlacesConfig{
+		MaxRetries:       2,
+		RetryBaseDelay:   10 * time.Millisecond,
+		RetryMaxDelay:    10 * time.Millisecond,
+		BreakerThreshold: 3,
+		BreakerCooldown:  time.Minute,
+	}, budget, nil, discardLogger)
+
+	return NewPlacesService(PlacesServiceOptions{
+		APIKey:  "test-key",
+		BaseURL: server.URL,
+		Client:  client,
+		Timeout: timeout,
+		Logger:  discardLogger,
+	})
+}
+
+func TestPlacesServiceAppliesCallDeadline(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	service := newTestPlacesService(server, 200*time.Millisecond, nil)
+	start := time.Now()
+	_, err := service.GetPlaceDetails(context.Background(), "place")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("call took %v, want about the 200ms deadline", elapsed)
+	}
+}
+
+func TestPlacesServiceDeadlineCoversRetries(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"places":[{"id":"a","displayName":{"text":"Cafe"}}]}`))
+	}))
+	defer server.Close()
+
+	// The retried call succeeds, and the body is read before the deadline
+	// is released
+	service := newTestPlacesService(server, time.Second, nil)
+	places, err := service.SearchNearby(context.Background(), 34.05, -118.24, 500, 10)
+	if err != nil {
+		t.Fatalf("search nearby: %v", err)
+	}
+	if len(places) != 1 || calls.Load() != 2 {
+		t.Errorf("got %d places after %d calls, want 1 after 2", len(places), calls.Load())
+	}
+}
+
+func TestPlacesBudgetChargesEveryAttempt(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"places":[]}`))
+	}))
+	defer server.Close()
+
+	// The first call and its retry spend the whole budget
+	budget := NewPlacesBudget(2, time.UTC, nil)
+	service := newTestPlacesService(server, time.Second, budget)
+	if _, err := service.SearchNearby(context.Background(), 34.05, -118.24, 500, 10); err != nil {
+		t.Fatalf("first search: %v", err)
+	}
+
+	_, err := service.SearchNearby(context.Background(), 34.05, -118.24, 500, 10)
+	if !errors.Is(err, ErrPlacesBudgetExhausted) {
+		t.Fatalf("err = %v, want ErrPlacesBudgetExhausted", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("upstream calls = %d, want 2", calls.Load())
+	}
+}
+
+func TestPlacesBudgetExhaustionIsNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	// The first attempt spends the budget and the retry is refused without
+	// being sent or tripping the breaker
+	budget := NewPlacesBudget(1, time.UTC, nil)
+	service := newTestPlacesService(server, time.Second, budget)
+	for i := 0; i < 5; i++ {
+		_, err := service.GetPlaceDetails(context.Background(), "place")
+		if i > 0 && !errors.Is(err, ErrPlacesBudgetExhausted) {
+			t.Fatalf("call %d err = %v, want ErrPlacesBudgetExhausted", i, err)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("upstream calls = %d, want 1", calls.Load())
+	}
+}
+
+func TestPlacesPingIsNotBilled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	budget := NewPlacesBudget(1, time.UTC, nil)
+	service := newTestPlacesService(server, time.Second, budget)
+	for i := 0; i < 3; i++ {
+		if err := service.Ping(context.Background()); err != nil {
+			t.Fatalf("ping: %v", err)
+		}
+	}
+	if err := budget.Take(context.Background()); err != nil {
+		t.Errorf("budget spent by pings: %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+// Package validation checks request parameters and bodies, collecting every
+// invalid field so clients can fix them all at once
+package validation
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// FieldError describes one invalid field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is returned when one or more fields are invalid
+type Error struct {
+	Fields []FieldError
+}
+
+// Error implements error
+func (e *Error) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		messages = append(messages, f.Field+": "+f.Message)
+	}
+	return "invalid fields: " + strings.Join(messages, "; ")
+}
+
+// OnlyCoordinates reports whether every invalid field is a latitude or longitude
+func (e *Error) OnlyCoordinates() bool {
+	for _, f := range e.Fields {
+		switch f.Field {
+		case "lat", "lng", "latitude", "longitude":
+		default:
+			return false
+		}
+	}
+	return len(e.Fields) > 0
+}
+
+// Validator accumulates field errors
+type Validator struct {
+	fields []FieldError
+}
+
+// New creates a Validator
+func New() *Validator {
+	return &Validator{}
+}
+
+// Add records an invalid field
+func (v *Validator) Add(field, message string) {
+	v.fields = append(v.fields, FieldError{Field: field, Message: message})
+}
+
+// Check records an invalid field unless ok is true
+func (v *Validator) Check(ok bool, field, message string) {
+	if !ok {
+		v.Add(field, message)
+	}
+}
+
+// Err returns an *Error listing every invalid field, or nil if all were valid
+func (v *Validator) Err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &Error{Fields: v.fields}
+}
+
+// Required checks that a string field is not blank and at most maxLength characters
+func (v *Validator) Required(field, value string, maxLength int) {
+	switch {
+	case strings.TrimSpace(value) == "":
+		v.Add(field, "is required")
+	case len([]rune(value)) > maxLength:
+		v.Add(field, "must be at most "+strconv.Itoa(maxLength)+" characters")
+	}
+}
+
+// Latitude checks that a latitude is within [-90, 90]
+func (v *Validator) Latitude(field string, value float64) {
+	v.Check(value >= -90 && value <= 90, field, "must be between -90 and 90")
+}
+
+// Longitude checks that a longitude is within [-180, 180]
+func (v *Validator) Longitude(field string, value float64) {
+	v.Check(value >= -180 && value <= 180, field, "must be between -180 and 180")
+}
+
+// QueryFloat parses an optional float query parameter within [min, max],
+// returning fallback if it is absent or invalid
+func (v *Validator) QueryFloat(query url.Values, name string, fallback, min, max float64) float64 {
+	raw := query.Get(name)
+	if raw == "" {
+		return fallback
+	}
+
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		v.Add(name, "must be a number")
+		return fallback
+	}
+	if value < min || value > max {
+		v.Add(name, "must be between "+formatFloat(min)+" and "+formatFloat(max))
+		return fallback
+	}
+	return value
+}
+
+// QueryInt parses an optional integer query parameter within [min, max],
+// returning fallback if it is absent or invalid
+func (v *Validator) QueryInt(query url.Values, name string, fallback, min, max int) int {
+	raw := query.Get(name)
+	if raw == "" {
+		return fallback
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		v.Add(name, "must be an integer")
+		return fallback
+	}
+	if value < min || value > max {
+		v.Add(name, "must be between "+strconv.Itoa(min)+" and "+strconv.Itoa(max))
+		return fallback
+	}
+	return value
+}
+
+// QueryBool parses an optional boolean query parameter, returning nil if it is absent or invalid
+func (v *Validator) QueryBool(query url.Values, name string) *bool {
+	raw := query.Get(name)
+	if raw == "" {
+		return nil
+	}
+
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		v.Add(name, "must be true or false")
+		return nil
+	}
+	return &value
+}
+
+// formatFloat formats a bound without trailing zeros
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
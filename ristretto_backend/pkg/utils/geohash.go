@@ -0,0 +1,51 @@
+package utils
+
+// geohashAlphabet is the base32 alphabet used by geohashes
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Geohash encodes a coordinate as a geohash of the given precision (number of characters)
+func Geohash(latitude, longitude float64, precision int) string {
+	hash, _, _ := geohashCell(latitude, longitude, precision)
+	return hash
+}
+
+// GeohashCenter returns the geohash of a coordinate along with the center of
+// its cell, which can be used to snap nearby coordinates onto the same point
+func GeohashCenter(latitude, longitude float64, precision int) (hash string, centerLat, centerLng float64) {
+	hash, latRange, lngRange := geohashCell(latitude, longitude, precision)
+	return hash, (latRange[0] + latRange[1]) / 2, (lngRange[0] + lngRange[1]) / 2
+}
+
+// geohashCell encodes a coordinate and returns the bounds of its cell
+func geohashCell(latitude, longitude float64, precision int) (string, [2]float64, [2]float64) {
+	latRange := [2]float64{-90, 90}
+	lngRange := [2]float64{-180, 180}
+
+	hash := make([]byte, 0, precision)
+	bit, ch, even := 0, 0, true
+
+	for len(hash) < precision {
+		// Even bits refine longitude, odd bits refine latitude
+		r, value := &latRange, latitude
+		if even {
+			r, value = &lngRange, longitude
+		}
+
+		mid := (r[0] + r[1]) / 2
+		ch <<= 1
+		if value >= mid {
+			ch |= 1
+			r[0] = mid
+		} else {
+			r[1] = mid
+		}
+		even = !even
+
+		if bit++; bit == 5 {
+			hash = append(hash, geohashAlphabet[ch])
+			bit, ch = 0, 0
+		}
+	}
+
+	return string(hash), latRange, lngRange
+}